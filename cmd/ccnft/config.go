@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+	"github.com/xyths/hs"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var configCommand = &cli.Command{
+	Name:  "config",
+	Usage: "Configuration of the CLI",
+	Subcommands: []*cli.Command{
+		{
+			Action: showConfig,
+			Name:   "show",
+			Usage:  "print the effective config, merged from file, CYBERCAR_* environment and flags, secrets redacted",
+			Flags: []cli.Flag{
+				accountFlag,
+			},
+		},
+		{
+			Action: validateConfig,
+			Name:   "validate",
+			Usage:  "check the effective config for typos: addresses, urls, mnemonic file permissions and log settings",
+			Flags: []cli.Flag{
+				accountFlag,
+			},
+		},
+		{
+			Action: initConfig,
+			Name:   "init",
+			Usage:  "create a config interactively, and check the contract responds on the rpc",
+			Flags: []cli.Flag{
+				configOutFlag,
+			},
+		},
+	},
+}
+
+// loadConfig reads the config file, then applies the network profile, the
+// CYBERCAR_* environment variables and the command line flags, in that order.
+// The environment is applied by the flags themselves, see flags.go.
+func loadConfig(ctx *cli.Context) (node.Config, error) {
+	cfg := node.Config{}
+	if err := hs.ParseJsonConfig(ctx.String(ConfigFlag.Name), &cfg); err != nil {
+		return cfg, err
+	}
+	network := cfg.Network
+	if v := ctx.String(NetworkFlag.Name); v != "" {
+		network = v
+	}
+	if network != "" {
+		if err := cfg.UseNetwork(network); err != nil {
+			return cfg, err
+		}
+	}
+	if v := ctx.String(RPCFlag.Name); v != "" {
+		cfg.RPC = v
+	}
+	if v := ctx.String(ContractFlag.Name); v != "" {
+		cfg.Contract = v
+	}
+	if v := ctx.String(MnemonicFlag.Name); v != "" {
+		cfg.Mnemonic = v
+	}
+	if ctx.IsSet(accountFlag.Name) {
+		cfg.Account = ctx.Int(accountFlag.Name)
+	}
+	return cfg, nil
+}
+
+func showConfig(ctx *cli.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	cfg.RPC = redactURL(cfg.RPC)
+	cfg.Signer = redactURL(cfg.Signer)
+	networks := make(map[string]node.Network)
+	for name, network := range cfg.Networks {
+		network.RPC = redactURL(network.RPC)
+		networks[name] = network
+	}
+	cfg.Networks = networks
+	channels := make([]node.NotifyChannel, len(cfg.Notify.Channels))
+	for i, c := range cfg.Notify.Channels {
+		c.URL = redactURL(c.URL)
+		if c.Secret != "" {
+			c.Secret = "***"
+		}
+		channels[i] = c
+	}
+	cfg.Notify.Channels = channels
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// redactURL hides the credentials and api keys often embedded in rpc urls,
+// in the user info, the query, or the last path segment (e.g. infura /v3/<key>).
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User("***")
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q.Set(k, "***")
+		}
+		u.RawQuery = q.Encode()
+	}
+	if i := strings.LastIndex(u.Path, "/"); i >= 0 && len(u.Path)-i-1 >= 16 {
+		u.Path = u.Path[:i+1] + "***"
+	}
+	return strings.NewReplacer("%2A", "*").Replace(u.String())
+}
+
+func validateConfig(ctx *cli.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	err = cfg.Validate()
+	var ve *node.ValidationError
+	if errors.As(err, &ve) {
+		for _, p := range ve.Problems {
+			fmt.Println(p)
+		}
+		return fmt.Errorf("%d problems in config", len(ve.Problems))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("config ok")
+	return nil
+}
+
+// initConfig asks for the essential settings, probes the contract on the rpc
+// and writes the config file.
+func initConfig(ctx *cli.Context) error {
+	out := ctx.String(configOutFlag.Name)
+	in := bufio.NewScanner(ctx.App.Reader)
+	w := ctx.App.Writer
+	ask := func(question, def string) string {
+		if def != "" {
+			_, _ = fmt.Fprintf(w, "%s [%s]: ", question, def)
+		} else {
+			_, _ = fmt.Fprintf(w, "%s: ", question)
+		}
+		if !in.Scan() {
+			return def
+		}
+		if answer := strings.TrimSpace(in.Text()); answer != "" {
+			return answer
+		}
+		return def
+	}
+	yes := func(question string) bool {
+		answer := strings.ToLower(ask(question+" [y/N]", ""))
+		return answer == "y" || answer == "yes"
+	}
+
+	if _, err := os.Stat(out); err == nil && !yes(fmt.Sprintf("%s exists, overwrite?", out)) {
+		return fmt.Errorf("%s exists", out)
+	}
+	cfg := node.Config{
+		Log: hs.LogConf{Level: "info", Outputs: []string{"stdout"}, Errors: []string{"stderr"}},
+	}
+	cfg.RPC = ask("rpc url", "http://localhost:8545")
+	cfg.Contract = ask("contract address", "")
+	cfg.Mnemonic = ask("mnemonic file, empty for read only", "")
+	if cfg.Mnemonic != "" {
+		account, err := strconv.Atoi(ask("hd account index", "0"))
+		if err != nil {
+			return fmt.Errorf("bad account index: %w", err)
+		}
+		cfg.Account = account
+	}
+	cfg.Log.Level = ask("log level", cfg.Log.Level)
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "checking contract %s on %s\n", cfg.Contract, redactURL(cfg.RPC))
+	info, err := node.Probe(ctx.Context, cfg.RPC, cfg.Contract)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "check failed: %s\n", err)
+		if !yes("write the config anyway?") {
+			return err
+		}
+	} else {
+		_, _ = fmt.Fprintf(w, "chain %s, %s (%s) version %s\n", info.ChainID, info.Name, info.Symbol, info.Version)
+		// pin the chain, so the config can't be used against another one by mistake
+		cfg.ChainID = info.ChainID.Uint64()
+	}
+
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	// the rpc url may carry an api key
+	if err = ioutil.WriteFile(out, b, 0600); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "config written to %s, use it with -c %s\n", out, out)
+	return nil
+}
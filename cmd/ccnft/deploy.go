@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+	"io/ioutil"
+	"os"
+)
+
+var deployCommand = &cli.Command{
+	Before: deployerNode,
+	Action: deploy,
+	Name:   "deploy",
+	Usage:  "deploy the contract from an artifact, initialize it, and write its address into the network profile",
+	Flags: []cli.Flag{
+		artifactFlag,
+		replaceFlag,
+		accountFlag,
+	},
+}
+
+func deploy(ctx *cli.Context) error {
+	file := ctx.String(artifactFlag.Name)
+	if file == "" {
+		return errors.New("input the contract artifact with --artifact")
+	}
+	artifact, err := cyber.ReadArtifact(file)
+	if err != nil {
+		return err
+	}
+	if cn.Config().Contract != "" {
+		deployed, err := cn.DeployedAt(ctx.Context, nil)
+		if err != nil {
+			return err
+		}
+		if deployed && !ctx.Bool(replaceFlag.Name) {
+			return fmt.Errorf("contract %s is already deployed, use --replace to deploy a new one in its place", cn.Config().Contract)
+		}
+	}
+	d, err := cn.Deploy(ctx.Context, artifact)
+	if d != nil {
+		fmt.Printf("Contract: %s\nTx: %s\nBlock: %d\n", d.Address.Hex(), d.Tx.Hex(), d.Block)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Name: %s\nSymbol: %s\nVersion: %s\nOwner: %s\n", d.Name, d.Symbol, d.Version, d.Owner.Hex())
+
+	network := cn.Config().Network
+	config := ctx.String(ConfigFlag.Name)
+	if err = saveDeployment(config, network, d); err != nil {
+		return fmt.Errorf("deployed, but writing %s failed, set the contract by hand: %w", config, err)
+	}
+	if network == "" {
+		fmt.Printf("contract written to %s\n", config)
+	} else {
+		fmt.Printf("contract written to network %s in %s\n", network, config)
+	}
+	return nil
+}
+
+// saveDeployment writes the contract, its deploy block and chain into the
+// network profile of the config file, or its top level without a network.
+// The file is edited as raw json, in its key order, so the rest of it is kept
+// as it is, and the environment and flag overrides are not written.
+func saveDeployment(file, network string, d *node.Deployment) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var root jsonObject
+	if err = json.Unmarshal(b, &root); err != nil {
+		return err
+	}
+	set := func(o *jsonObject) error {
+		for _, m := range []struct {
+			key   string
+			value interface{}
+		}{
+			{"contract", d.Address.Hex()},
+			{"deployBlock", d.Block},
+			{"chainId", d.ChainID},
+		} {
+			raw, err := json.Marshal(m.value)
+			if err != nil {
+				return err
+			}
+			o.set(m.key, raw)
+		}
+		return nil
+	}
+	if network == "" {
+		err = set(&root)
+	} else {
+		var networks, profile jsonObject
+		if err = json.Unmarshal(root.get("networks"), &networks); err != nil {
+			return err
+		}
+		raw := networks.get(network)
+		if raw == nil {
+			return fmt.Errorf("network %s not found in %s", network, file)
+		}
+		if err = json.Unmarshal(raw, &profile); err != nil {
+			return err
+		}
+		if err = set(&profile); err != nil {
+			return err
+		}
+		if raw, err = json.Marshal(profile); err != nil {
+			return err
+		}
+		networks.set(network, raw)
+		if raw, err = json.Marshal(networks); err != nil {
+			return err
+		}
+		root.set("networks", raw)
+	}
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(out, '\n'), info.Mode().Perm())
+}
+
+// jsonObject is a json object which keeps the order of its keys.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+func (o jsonObject) get(key string) json.RawMessage {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+// set replaces the value of key in place, or appends it.
+func (o *jsonObject) set(key string, value json.RawMessage) {
+	for i, m := range *o {
+		if m.Key == key {
+			(*o)[i].Value = value
+			return
+		}
+	}
+	*o = append(*o, jsonMember{Key: key, Value: value})
+}
+
+func (o *jsonObject) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("want a json object, got %v", t)
+	}
+	*o = nil
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		o.set(t.(string), value)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(m.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
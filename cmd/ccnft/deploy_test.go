@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/ethereum/go-ethereum/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveDeployment(t *testing.T) {
+	const config = `{
+  "rpc": "http://localhost:8545",
+  "mnemonic": "config/mnemonic",
+  "networks": {
+    "mainnet": {
+      "rpc": "https://mainnet.example",
+      "chainId": 1
+    },
+    "local": {
+      "rpc": "http://localhost:8545",
+      "contract": "0x0000000000000000000000000000000000000001",
+      "chainId": 1337
+    }
+  },
+  "log": {
+    "level": "info"
+  }
+}
+`
+	d := &node.Deployment{Address: common.HexToAddress("0x00000000000000000000000000000000000c0de0"), Block: 42, ChainID: 1337}
+	for network, want := range map[string]string{
+		"local": `{
+  "rpc": "http://localhost:8545",
+  "mnemonic": "config/mnemonic",
+  "networks": {
+    "mainnet": {
+      "rpc": "https://mainnet.example",
+      "chainId": 1
+    },
+    "local": {
+      "rpc": "http://localhost:8545",
+      "contract": "0x00000000000000000000000000000000000c0De0",
+      "chainId": 1337,
+      "deployBlock": 42
+    }
+  },
+  "log": {
+    "level": "info"
+  }
+}
+`,
+		"": `{
+  "rpc": "http://localhost:8545",
+  "mnemonic": "config/mnemonic",
+  "networks": {
+    "mainnet": {
+      "rpc": "https://mainnet.example",
+      "chainId": 1
+    },
+    "local": {
+      "rpc": "http://localhost:8545",
+      "contract": "0x0000000000000000000000000000000000000001",
+      "chainId": 1337
+    }
+  },
+  "log": {
+    "level": "info"
+  },
+  "contract": "0x00000000000000000000000000000000000c0De0",
+  "deployBlock": 42,
+  "chainId": 1337
+}
+`,
+	} {
+		file := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(file, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveDeployment(file, network, d); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("network %q:\n%s\nwant\n%s", network, got, want)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDeployment(file, "goerli", d); err == nil {
+		t.Error("unknown network: want an error")
+	}
+}
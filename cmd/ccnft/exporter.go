@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+	"net/http"
+	"time"
+)
+
+var exporterCommand = &cli.Command{
+	Before: readOnlyNode,
+	Action: exporter,
+	Name:   "exporter",
+	Usage:  "serve the contract state, holders, transfers and rpc health as Prometheus metrics",
+	Flags: []cli.Flag{
+		listenFlag,
+		intervalFlag,
+		watchConfirmationsFlag,
+	},
+}
+
+func exporter(ctx *cli.Context) error {
+	e, err := cn.NewExporter()
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e.Handler())
+	srv := &http.Server{Addr: ctx.String(listenFlag.Name), Handler: mux}
+	failed := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+	fmt.Printf("serving metrics on %s/metrics\n", srv.Addr)
+
+	runCtx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Run(runCtx, node.ExporterOptions{
+			Interval:      ctx.Duration(intervalFlag.Name),
+			Confirmations: ctx.Uint64(watchConfirmationsFlag.Name),
+		})
+	}()
+	select {
+	case err = <-failed:
+		cancel()
+		<-done
+	case err = <-done:
+	}
+	shutdown, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	_ = srv.Shutdown(shutdown)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/urfave/cli/v2"
+import (
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+	"time"
+)
 
 var (
 	ConfigFlag = &cli.StringFlag{
@@ -8,20 +12,198 @@ var (
 		Aliases: []string{"c"},
 		Value:   "config.json",
 		Usage:   "load configuration from `file`",
+		EnvVars: []string{"CYBERCAR_CONFIG"},
+	}
+	NetworkFlag = &cli.StringFlag{
+		Name:    "network",
+		Usage:   "use the network profile `name` in the config, e.g. mainnet, goerli, local",
+		EnvVars: []string{"CYBERCAR_NETWORK"},
+	}
+	RPCFlag = &cli.StringFlag{
+		Name:    "rpc",
+		Usage:   "rpc `url`, overrides the config",
+		EnvVars: []string{"CYBERCAR_RPC"},
+	}
+	ContractFlag = &cli.StringFlag{
+		Name:    "contract",
+		Usage:   "contract `address`, overrides the config",
+		EnvVars: []string{"CYBERCAR_CONTRACT"},
+	}
+	MnemonicFlag = &cli.StringFlag{
+		Name:    "mnemonic",
+		Usage:   "mnemonic `file`, overrides the config",
+		EnvVars: []string{"CYBERCAR_MNEMONIC"},
 	}
 	OwnerFlag = &cli.StringFlag{
 		Name:    "owner",
 		Aliases: []string{"r"},
-		Usage:   "owner address",
+		Usage:   "owner address or ENS name",
 	}
 	addressListFlag = &cli.StringFlag{
 		Name:    "addressList",
 		Aliases: []string{"f"},
-		Usage:   "owner address list `file`, in csv format",
+		Usage:   "owner address list `file`, in csv format, ENS names allowed",
 	}
 	amountFlag = &cli.IntFlag{
 		Name:    "amount",
 		Aliases: []string{"m"},
 		Usage:   "amount of NFT",
 	}
+	unsignedOutFlag = &cli.StringFlag{
+		Name:  "unsigned-out",
+		Usage: "write the prepared unsigned transaction to `file` instead of sending it",
+	}
+	inputFlag = &cli.StringFlag{
+		Name:     "in",
+		Aliases:  []string{"i"},
+		Usage:    "input transaction `file`",
+		Required: true,
+	}
+	outputFlag = &cli.StringFlag{
+		Name:    "out",
+		Aliases: []string{"o"},
+		Usage:   "output `file`",
+	}
+	keystoreFlag = &cli.StringFlag{
+		Name:  "keystore",
+		Usage: "sign with the key in keystore `file` instead of the mnemonic",
+	}
+	passwordFlag = &cli.StringFlag{
+		Name:  "password",
+		Usage: "read the keystore password from `file`",
+	}
+	safeFlag = &cli.StringFlag{
+		Name:  "safe",
+		Usage: "Safe multisig `address` owning the contract, print the SafeTx hash for it",
+	}
+	safeOutFlag = &cli.StringFlag{
+		Name:  "safe-out",
+		Usage: "write the calls as a Safe Transaction Builder batch to `file` instead of sending",
+	}
+	safeNonceFlag = &cli.Uint64Flag{
+		Name:  "safe-nonce",
+		Usage: "Safe `nonce` for the SafeTx hash, read from chain if not set",
+	}
+	accountFlag = &cli.IntFlag{
+		Name:    "account",
+		Usage:   "use the hd account with `index`, overrides the config",
+		EnvVars: []string{"CYBERCAR_ACCOUNT"},
+	}
+	derivationPathFlag = &cli.StringFlag{
+		Name:  "path",
+		Usage: "derivation path `template`: default, ledger-live, legacy, or a custom path with one %d",
+	}
+	countFlag = &cli.IntFlag{
+		Name:    "count",
+		Aliases: []string{"n"},
+		Value:   10,
+		Usage:   "number of accounts",
+	}
+	dryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "simulate the calls from the contract owner, report revert reason, gas, fee and state changes",
+	}
+	configOutFlag = &cli.StringFlag{
+		Name:    "out",
+		Aliases: []string{"o"},
+		Value:   "config/config.json",
+		Usage:   "write the config to `file`",
+	}
+	blockFlag = &cli.Uint64Flag{
+		Name:  "block",
+		Usage: "read the state at block `number`, the latest block if not set",
+	}
+	formatFlag = &cli.StringFlag{
+		Name:  "format",
+		Value: "table",
+		Usage: "output `format`: table or json",
+	}
+	uriFlag = &cli.BoolFlag{
+		Name:  "uri",
+		Usage: "resolve the tokenURI of every token",
+	}
+	metadataFlag = &cli.BoolFlag{
+		Name:  "metadata",
+		Usage: "fetch the metadata of every token for its name and image, implies --uri",
+	}
+	reverseFlag = &cli.BoolFlag{
+		Name:  "reverse",
+		Usage: "look up the primary ENS name of every owner",
+	}
+	ipfsGatewayFlag = &cli.StringFlag{
+		Name:  "ipfs-gateway",
+		Value: node.DefaultIPFSGateway,
+		Usage: "gateway `url` for ipfs:// uris",
+	}
+	kindFlag = &cli.StringFlag{
+		Name:  "kind",
+		Value: node.QuotaWhitelist,
+		Usage: "quota `kind`: whitelist or airdrop",
+	}
+	reportFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Value: "table",
+		Usage: "output `format`: table, csv or json",
+	}
+	planFlag = &cli.StringFlag{
+		Name:  "plan",
+		Usage: "refuse to apply if the chain no longer matches the plan saved in `file`",
+	}
+	forceFlag = &cli.BoolFlag{
+		Name:  "force",
+		Usage: "go on with the valid addresses despite rejected rows and warnings in the list",
+	}
+	artifactFlag = &cli.StringFlag{
+		Name:  "artifact",
+		Usage: "compiled contract `file`, a hardhat, truffle or foundry artifact json",
+	}
+	replaceFlag = &cli.BoolFlag{
+		Name:  "replace",
+		Usage: "deploy even though the contract in the config is deployed, and replace it",
+	}
+	asFlag = &cli.StringFlag{
+		Name:  "as",
+		Usage: "with --safe or --safe-out, check the contract owner is `address` (or ENS name), e.g. the Safe, instead of the signer account",
+	}
+	scheduleFlag = &cli.StringFlag{
+		Name:  "schedule",
+		Usage: "schedule `file`, one \"<time> <action>\" per line, e.g. \"2021-11-20T12:00:00Z setPhase 1\"",
+	}
+	progressFlag = &cli.StringFlag{
+		Name:  "progress",
+		Usage: "progress `file` of the schedule, default the schedule file name with .progress.json",
+	}
+	retriesFlag = &cli.IntFlag{
+		Name:  "retries",
+		Value: 3,
+		Usage: "retry a failed scheduled action `n` times",
+	}
+	retryDelayFlag = &cli.DurationFlag{
+		Name:  "retry-delay",
+		Value: 30 * time.Second,
+		Usage: "wait `duration` before a retry",
+	}
+	listFlag = &cli.BoolFlag{
+		Name:  "list",
+		Usage: "list the schedule with its progress and exit",
+	}
+	fromBlockFlag = &cli.Uint64Flag{
+		Name:  "from-block",
+		Usage: "start watching at block `number`, default the latest block",
+	}
+	intervalFlag = &cli.DurationFlag{
+		Name:  "interval",
+		Value: 15 * time.Second,
+		Usage: "poll the chain every `duration`",
+	}
+	watchConfirmationsFlag = &cli.Uint64Flag{
+		Name:  "confirmations",
+		Value: 2,
+		Usage: "read the events `n` blocks behind the latest block",
+	}
+	listenFlag = &cli.StringFlag{
+		Name:  "listen",
+		Value: ":9101",
+		Usage: "serve /metrics on `address`",
+	}
 )
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+func formatEther(wei *big.Int) string {
+	f := new(big.Float).SetInt(wei)
+	return f.Quo(f, big.NewFloat(1e18)).Text('f', 6)
+}
+
+func formatGwei(wei *big.Int) string {
+	f := new(big.Float).SetInt(wei)
+	return f.Quo(f, big.NewFloat(1e9)).Text('f', 2)
+}
+
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
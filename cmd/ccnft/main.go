@@ -22,9 +22,18 @@ func init() {
 	app.Commands = []*cli.Command{
 		userCommand,
 		adminCommand,
+		txCommand,
+		walletCommand,
+		configCommand,
+		watchCommand,
+		exporterCommand,
 	}
 	app.Flags = []cli.Flag{
 		ConfigFlag,
+		NetworkFlag,
+		RPCFlag,
+		ContractFlag,
+		MnemonicFlag,
 	}
 }
 
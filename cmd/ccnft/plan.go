@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+	"io/ioutil"
+	"math/big"
+)
+
+var (
+	planCommand = &cli.Command{
+		Before: readOnlyNode,
+		Action: planQuota,
+		Name:   "plan",
+		Usage:  "diff a desired state csv (address, cap) against the on-chain quotas, print the calls needed",
+		Flags: []cli.Flag{
+			addressListFlag,
+			kindFlag,
+			blockFlag,
+			outputFlag,
+		},
+	}
+	applyCommand = &cli.Command{
+		Before: adminNode,
+		Action: applyQuota,
+		Name:   "apply",
+		Usage:  "send only the calls needed to reach the desired state csv (address, cap)",
+		Flags: []cli.Flag{
+			addressListFlag,
+			kindFlag,
+			planFlag,
+			forceFlag,
+			unsignedOutFlag,
+			safeFlag,
+			safeOutFlag,
+			safeNonceFlag,
+			accountFlag,
+			dryRunFlag,
+			asFlag,
+		},
+	}
+)
+
+func planQuota(ctx *cli.Context) error {
+	block, err := cn.PinBlock(ctx.Context, blockNumber(ctx))
+	if err != nil {
+		return err
+	}
+	desired, err := readDesiredList(ctx, block, false)
+	if err != nil {
+		return err
+	}
+	plan, err := cn.PlanQuota(ctx.Context, ctx.String(kindFlag.Name), desired, block)
+	if err != nil {
+		return err
+	}
+	printPlan(plan)
+	if out := ctx.String(outputFlag.Name); out != "" {
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(out, b, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("plan written to %s, apply it with --plan %s\n", out, out)
+	}
+	return nil
+}
+
+func applyQuota(ctx *cli.Context) error {
+	desired, err := readDesiredList(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	kind := ctx.String(kindFlag.Name)
+	plan, err := cn.PlanQuota(ctx.Context, kind, desired, nil)
+	if err != nil {
+		return err
+	}
+	if file := ctx.String(planFlag.Name); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		saved := &node.QuotaPlan{}
+		if err = json.Unmarshal(b, saved); err != nil {
+			return err
+		}
+		if !plan.Equal(saved) {
+			printPlan(plan)
+			return fmt.Errorf("the chain changed since the plan at block %s was made, review the plan above and run plan again", saved.Block)
+		}
+	}
+	printPlan(plan)
+	if plan.Empty() {
+		return nil
+	}
+	if err = cn.ApplyQuota(ctx.Context, plan); err != nil {
+		return err
+	}
+	return finishAdmin(ctx, cn, "apply "+kind)
+}
+
+func printPlan(plan *node.QuotaPlan) {
+	changes := 0
+	for _, g := range plan.Groups {
+		changes += len(g.Changes)
+	}
+	fmt.Printf("%s plan at block %s: %d to change, %d unchanged, %d conflicts\n", plan.Kind, plan.Block, changes, plan.Unchanged, len(plan.Conflicts))
+	method := "addWhitelist"
+	if plan.Kind == node.QuotaAirdrop {
+		method = "addAirdrop"
+	}
+	for _, g := range plan.Groups {
+		fmt.Printf("  %s amount %d: %d addresses in %d transactions\n", method, g.Amount, len(g.Changes), cn.Chunks(len(g.Changes)))
+		for _, c := range g.Changes {
+			fmt.Printf("    ~ %s cap %d -> %d (minted %d)\n", c.Address.Hex(), c.Cap, c.Want, c.Minted)
+		}
+	}
+	for _, c := range plan.Conflicts {
+		fmt.Printf("  ! %s wants cap %d but minted %d already, skipped\n", c.Address.Hex(), c.Want, c.Minted)
+	}
+	if plan.Empty() {
+		fmt.Println("No changes, the quotas match the desired state")
+	}
+}
+
+// readDesiredList reads the desired state, every row needs its cap.
+func readDesiredList(ctx *cli.Context, block *big.Int, send bool) ([]node.DesiredQuota, error) {
+	list, err := loadList(ctx, block, true, send)
+	if err != nil {
+		return nil, err
+	}
+	var desired []node.DesiredQuota
+	for _, e := range list.Entries {
+		desired = append(desired, node.DesiredQuota{Address: e.Address, Cap: e.Amount})
+	}
+	return desired, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"os"
+	"time"
+)
+
+// progressPrinter prints the progress of waiting transactions to stderr, on
+// every state change, and every 10 seconds while pending.
+func progressPrinter() func(node.WaitStatus) {
+	var last node.WaitStatus
+	return func(s node.WaitStatus) {
+		if s.Hash == last.Hash && s.State == last.State && s.Confirmations == last.Confirmations &&
+			s.Elapsed-last.Elapsed < 10*time.Second {
+			return
+		}
+		last = s
+		_, _ = fmt.Fprintln(os.Stderr, s)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var scheduleCommand = &cli.Command{
+	Before: signingNode,
+	Action: schedule,
+	Name:   "schedule",
+	Usage:  "run a schedule of setPhase, pause, unpause and withdraw, each at its time, until all are done",
+	Flags: []cli.Flag{
+		scheduleFlag,
+		progressFlag,
+		retriesFlag,
+		retryDelayFlag,
+		listFlag,
+	},
+}
+
+func schedule(ctx *cli.Context) error {
+	file := ctx.String(scheduleFlag.Name)
+	if file == "" {
+		return errors.New("input the schedule with --schedule")
+	}
+	entries, err := node.LoadSchedule(file)
+	if err != nil {
+		return err
+	}
+	progress := ctx.String(progressFlag.Name)
+	if progress == "" {
+		progress = file + ".progress.json"
+	}
+	if ctx.Bool(listFlag.Name) {
+		return listSchedule(entries, progress)
+	}
+	// fail now rather than at the first action
+	if err = cn.CheckOwner(ctx.Context, common.Address{}); err != nil {
+		return err
+	}
+	opts := node.ScheduleOptions{
+		Progress:   progress,
+		Retries:    ctx.Int(retriesFlag.Name),
+		RetryDelay: ctx.Duration(retryDelayFlag.Name),
+		Notify:     printScheduleResult,
+	}
+	if err = cn.RunSchedule(ctx.Context, entries, opts); err != nil {
+		return err
+	}
+	fmt.Println("schedule finished")
+	return nil
+}
+
+func listSchedule(entries []node.ScheduleEntry, progress string) error {
+	records, err := node.LoadScheduleProgress(progress)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Line\tTime\tAction\tStatus\tNote")
+	for _, e := range entries {
+		status, note := "pending", ""
+		if r, ok := records[e.Key()]; ok {
+			status, note = r.Status, r.Note
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", e.Line, e.At.Local().Format(time.RFC3339), e, status, note)
+	}
+	return w.Flush()
+}
+
+func printScheduleResult(r node.ScheduleResult) {
+	switch {
+	case r.Err != nil:
+		fmt.Printf("%s FAILED after %d attempts: %s\n", r.Entry, r.Attempts, r.Err)
+	case r.Status == node.ScheduleSkipped:
+		fmt.Printf("%s skipped: %s\n", r.Entry, r.Note)
+	default:
+		fmt.Printf("%s done, %s after its time\n", r.Entry, r.Late.Round(time.Second))
+	}
+}
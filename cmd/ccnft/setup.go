@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+	"os"
+)
+
+// cn is the Node of this invocation, set up once by the Before hook of the command.
+var cn *node.Node
+
+// readOnlyNode is the Before hook of the query commands, they run without any
+// key material.
+func readOnlyNode(ctx *cli.Context) error {
+	return setupNode(ctx, node.NewReadOnly)
+}
+
+// signingNode is the Before hook of the commands which may send, the wallet is
+// loaded when the first transaction is signed.
+func signingNode(ctx *cli.Context) error {
+	if err := setupNode(ctx, node.New); err != nil {
+		return err
+	}
+	cn.SetProgress(progressPrinter())
+	return nil
+}
+
+// deployerNode is the Before hook of deploy, the config may have no contract yet.
+func deployerNode(ctx *cli.Context) error {
+	if err := setupNode(ctx, node.NewDeployer); err != nil {
+		return err
+	}
+	cn.SetProgress(progressPrinter())
+	return nil
+}
+
+func setupNode(ctx *cli.Context, newNode func(node.Config) *node.Node) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	s := newNode(cfg)
+	if err = s.Init(ctx.Context); err != nil {
+		return err
+	}
+	cn = s
+	return nil
+}
+
+// adminNode is the Before hook of the admin commands, honoring the
+// --unsigned-out, --safe/--safe-out and --dry-run modes, and checking the
+// contract owner.
+func adminNode(ctx *cli.Context) error {
+	// the calls are sent, or prepared, from the signer account, which --as
+	// would leave unchecked; only a Safe executes them as another account
+	if ctx.String(asFlag.Name) != "" && ctx.String(safeFlag.Name) == "" && ctx.String(safeOutFlag.Name) == "" {
+		return errors.New("--as is only for calls executed by a Safe, use it with --safe or --safe-out")
+	}
+	if err := signingNode(ctx); err != nil {
+		return err
+	}
+	s := cn
+	s.SetUnsignedOut(ctx.String(unsignedOutFlag.Name))
+	s.SetDryRun(ctx.Bool(dryRunFlag.Name))
+	if safe := ctx.String(safeFlag.Name); safe != "" || ctx.String(safeOutFlag.Name) != "" {
+		if safe != "" && !common.IsHexAddress(safe) {
+			return fmt.Errorf("bad safe address %s", safe)
+		}
+		s.StartSafeBatch(common.HexToAddress(safe))
+	}
+
+	as := ctx.String(asFlag.Name)
+	if as == "" {
+		as = ctx.String(safeFlag.Name)
+	}
+	var owner common.Address
+	if as != "" {
+		var err error
+		if owner, err = parseAddress(ctx, "owner", as, nil); err != nil {
+			return err
+		}
+	}
+	// a Safe batch without a Safe address is checked when imported into the Safe
+	if as != "" || ctx.String(safeOutFlag.Name) == "" {
+		if err := s.CheckOwner(ctx.Context, owner); err != nil {
+			if !ctx.Bool(dryRunFlag.Name) {
+				return err
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"strings"
+	"testing"
+)
+
+func TestAdminNodeAs(t *testing.T) {
+	app := &cli.App{
+		Commands: []*cli.Command{{
+			Name:   "pause",
+			Before: adminNode,
+			Action: func(*cli.Context) error { return nil },
+			Flags:  []cli.Flag{ConfigFlag, asFlag, safeFlag, safeOutFlag, unsignedOutFlag, dryRunFlag},
+		}},
+	}
+	owner := "0x00000000000000000000000000000000000a11ce"
+	for _, args := range [][]string{
+		{"pause", "--as", owner},
+		{"pause", "--as", owner, "--unsigned-out", "tx.json"},
+		{"pause", "--as", owner, "--dry-run"},
+	} {
+		err := app.Run(append([]string{"ccnft"}, args...))
+		if err == nil || !strings.Contains(err.Error(), "--as") {
+			t.Errorf("%v: err = %v, want --as refused", args, err)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/cybercar-nft/go-cybercar/node"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/urfave/cli/v2"
-	"github.com/xyths/hs"
+	"math/big"
 	"os"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 )
 
 var (
@@ -21,6 +25,7 @@ var (
 		},
 		Subcommands: []*cli.Command{
 			{
+				Before: readOnlyNode,
 				Action: airdropQuota,
 				Name:   "airdropQuota",
 				Usage:  "check airdrop quota of an owner",
@@ -29,16 +34,45 @@ var (
 				},
 			},
 			{
+				Before: readOnlyNode,
+				Action: info,
+				Name:   "info",
+				Usage:  "collection overview, all read at one block",
+				Flags: []cli.Flag{
+					blockFlag,
+					formatFlag,
+				},
+			},
+			{
+				Before: readOnlyNode,
+				Action: tokens,
+				Name:   "tokens",
+				Usage:  "list the tokens of an owner, or of every owner in a csv",
+				Flags: []cli.Flag{
+					OwnerFlag,
+					addressListFlag,
+					blockFlag,
+					uriFlag,
+					metadataFlag,
+					ipfsGatewayFlag,
+					reverseFlag,
+					formatFlag,
+				},
+			},
+			{
+				Before: readOnlyNode,
 				Action: paused,
 				Name:   "paused",
 				Usage:  "check if contract paused",
 			},
 			{
+				Before: readOnlyNode,
 				Action: phase,
 				Name:   "phase",
 				Usage:  "check mint phase",
 			},
 			{
+				Before: readOnlyNode,
 				Action: mintQuota,
 				Name:   "mintQuota",
 				Usage:  "check mint quota of an whitelist owner",
@@ -56,57 +90,114 @@ var (
 		},
 		Subcommands: []*cli.Command{
 			{
+				Before: adminNode,
 				Action: addAirdrop,
 				Name:   "addAirdrop",
 				Usage:  "add airdrop list with quota",
 				Flags: []cli.Flag{
 					addressListFlag,
 					amountFlag,
+					forceFlag,
+					unsignedOutFlag,
+					safeFlag,
+					safeOutFlag,
+					safeNonceFlag,
+					accountFlag,
+					dryRunFlag,
+					asFlag,
 				},
 			},
 			{
+				Before: adminNode,
 				Action: addWhitelist,
 				Name:   "addWhitelist",
 				Usage:  "add mint whitelist with quota",
 				Flags: []cli.Flag{
 					addressListFlag,
 					amountFlag,
+					forceFlag,
+					unsignedOutFlag,
+					safeFlag,
+					safeOutFlag,
+					safeNonceFlag,
+					accountFlag,
+					dryRunFlag,
+					asFlag,
 				},
 			},
 			{
+				Before: adminNode,
 				Action: pause,
 				Name:   "pause",
 				Usage:  "pause",
+				Flags: []cli.Flag{
+					unsignedOutFlag,
+					safeFlag,
+					safeOutFlag,
+					safeNonceFlag,
+					accountFlag,
+					dryRunFlag,
+					asFlag,
+				},
 			},
 			{
+				Before: adminNode,
 				Action: unpause,
 				Name:   "unpause",
 				Usage:  "unpause",
+				Flags: []cli.Flag{
+					unsignedOutFlag,
+					safeFlag,
+					safeOutFlag,
+					safeNonceFlag,
+					accountFlag,
+					dryRunFlag,
+					asFlag,
+				},
 			},
 			{
+				Before: adminNode,
 				Action: setPhase,
 				Name:   "setPhase",
 				Usage:  "set phase of operation",
 				Flags: []cli.Flag{
+					unsignedOutFlag,
+					safeFlag,
+					safeOutFlag,
+					safeNonceFlag,
+					accountFlag,
+					dryRunFlag,
+					asFlag,
 				},
 				ArgsUsage: "phase (0-2)",
 			},
+			{
+				Before: readOnlyNode,
+				Action: auditQuota,
+				Name:   "auditQuota",
+				Usage:  "read the quota of every address in a list at one block, report who is not added or fully used",
+				Flags: []cli.Flag{
+					addressListFlag,
+					kindFlag,
+					blockFlag,
+					reportFormatFlag,
+					outputFlag,
+				},
+			},
+			planCommand,
+			applyCommand,
+			deployCommand,
+			scheduleCommand,
 		},
 	}
 )
 
 func airdropQuota(ctx *cli.Context) error {
-	owner := ctx.String(OwnerFlag.Name)
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
-		return err
-	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+	owner, err := parseOwner(ctx, nil)
+	if err != nil {
 		return err
 	}
-	quota, err := s.AirdropQuota(ctx.Context, common.HexToAddress(owner))
+	quota, err := cn.AirdropQuota(ctx.Context, owner)
 	if err != nil {
 		return err
 	}
@@ -114,35 +205,125 @@ func airdropQuota(ctx *cli.Context) error {
 	return nil
 }
 
-func paused(ctx *cli.Context) error {
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
+func info(ctx *cli.Context) error {
+	i, err := cn.Info(ctx.Context, blockNumber(ctx))
+	if err != nil {
 		return err
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+	switch ctx.String(formatFlag.Name) {
+	case "json":
+		return printJSON(i)
+	case "table":
+	default:
+		return fmt.Errorf("unknown format %s", ctx.String(formatFlag.Name))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(k string, v interface{}) {
+		_, _ = fmt.Fprintf(w, "%s\t%v\n", k, v)
+	}
+	row("Contract", i.Contract.Hex())
+	row("Block", i.Block)
+	row("Name", i.Name)
+	row("Symbol", i.Symbol)
+	row("Version", i.Version)
+	row("Owner", i.Owner.Hex())
+	row("Paused", i.Paused)
+	row("Phase", i.Phase)
+	row("Capacity", i.Capacity)
+	row("TotalSupply", i.TotalSupply)
+	row("Reserved", i.Reserved)
+	row("WhitelistCap", i.WhitelistCap)
+	row("MintPrice", formatEther(i.MintPrice)+" ETH")
+	row("Balance", formatEther(i.Balance)+" ETH")
+	row("ERC721", i.ERC721)
+	row("ERC721Metadata", i.ERC721Metadata)
+	row("ERC721Enumerable", i.ERC721Enumerable)
+	return w.Flush()
+}
+
+func tokens(ctx *cli.Context) error {
+	// names resolve at the block the holdings are read at
+	block, err := cn.PinBlock(ctx.Context, blockNumber(ctx))
+	if err != nil {
 		return err
 	}
-	p, err := s.Paused(ctx.Context)
+	var owners []common.Address
+	if ctx.String(OwnerFlag.Name) != "" {
+		owner, err := parseOwner(ctx, block)
+		if err != nil {
+			return err
+		}
+		owners = append(owners, owner)
+	}
+	if ctx.String(addressListFlag.Name) != "" {
+		list, err := loadList(ctx, block, false, false)
+		if err != nil {
+			return err
+		}
+		owners = append(owners, list.Addresses()...)
+	}
+	if len(owners) == 0 {
+		return errors.New("input --owner or an address list file")
+	}
+	holdings, err := cn.Tokens(ctx.Context, owners, block)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Paused: %v", p)
-	return nil
+	if ctx.Bool(reverseFlag.Name) {
+		if err = cn.LookupHoldings(ctx.Context, holdings); err != nil {
+			return err
+		}
+	}
+	fetch := ctx.Bool(metadataFlag.Name)
+	if fetch || ctx.Bool(uriFlag.Name) {
+		cn.ResolveTokens(ctx.Context, holdings, fetch, ctx.String(ipfsGatewayFlag.Name))
+	}
+	switch ctx.String(formatFlag.Name) {
+	case "json":
+		return printJSON(holdings)
+	case "table":
+	default:
+		return fmt.Errorf("unknown format %s", ctx.String(formatFlag.Name))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Owner\tToken\tURI\tName\tImage\tError")
+	for _, h := range holdings {
+		owner := h.Owner.Hex()
+		if h.ENS != "" {
+			owner += " (" + h.ENS + ")"
+		}
+		if len(h.Tokens) == 0 {
+			_, _ = fmt.Fprintf(w, "%s\t-\t\t\t\t\n", owner)
+		}
+		for _, t := range h.Tokens {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", owner, t.ID, t.URI, t.Name, t.Image, t.Error)
+		}
+	}
+	if len(holdings) > 0 {
+		_, _ = fmt.Fprintf(w, "at block %s\n", holdings[0].Block)
+	}
+	return w.Flush()
 }
 
-func phase(ctx *cli.Context) error {
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
-		return err
+// blockNumber is the --block flag, nil for the latest block.
+func blockNumber(ctx *cli.Context) *big.Int {
+	if !ctx.IsSet(blockFlag.Name) {
+		return nil
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+	return new(big.Int).SetUint64(ctx.Uint64(blockFlag.Name))
+}
+
+func paused(ctx *cli.Context) error {
+	p, err := cn.Paused(ctx.Context)
+	if err != nil {
 		return err
 	}
-	p, err := s.Phase(ctx.Context)
+	fmt.Printf("Paused: %v", p)
+	return nil
+}
+
+func phase(ctx *cli.Context) error {
+	p, err := cn.Phase(ctx.Context)
 	if err != nil {
 		return err
 	}
@@ -151,17 +332,11 @@ func phase(ctx *cli.Context) error {
 }
 
 func mintQuota(ctx *cli.Context) error {
-	owner := ctx.String(OwnerFlag.Name)
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
-		return err
-	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+	owner, err := parseOwner(ctx, nil)
+	if err != nil {
 		return err
 	}
-	quota, err := s.MintQuota(ctx.Context, common.HexToAddress(owner))
+	quota, err := cn.MintQuota(ctx.Context, owner)
 	if err != nil {
 		return err
 	}
@@ -170,129 +345,276 @@ func mintQuota(ctx *cli.Context) error {
 }
 
 func addAirdrop(ctx *cli.Context) error {
-	amount := ctx.Int(amountFlag.Name)
-	addrFile := ctx.String(addressListFlag.Name)
-	f, err := os.Open(addrFile)
+	// rows without an amount take --amount
+	list, err := loadList(ctx, nil, !ctx.IsSet(amountFlag.Name), true)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	records, err := r.ReadAll()
+	for _, g := range list.ByAmount() {
+		if err = cn.AddAirdrop(ctx.Context, g.Addresses, g.Amount); err != nil {
+			return err
+		}
+	}
+	return finishAdmin(ctx, cn, "addAirdrop")
+}
+
+func addWhitelist(ctx *cli.Context) error {
+	// rows without an amount take --amount
+	list, err := loadList(ctx, nil, !ctx.IsSet(amountFlag.Name), true)
 	if err != nil {
 		return err
 	}
-	var owners []common.Address
-	for _, line := range records {
-		for _, token := range line {
-			owners = append(owners, common.HexToAddress(token))
+	for _, g := range list.ByAmount() {
+		if err = cn.AddWhitelist(ctx.Context, g.Addresses, g.Amount); err != nil {
+			return err
 		}
 	}
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err = hs.ParseJsonConfig(configFile, &cfg); err != nil {
+	return finishAdmin(ctx, cn, "addWhitelist")
+}
+
+func pause(ctx *cli.Context) error {
+	if err := cn.Pause(ctx.Context); err != nil {
 		return err
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+	return finishAdmin(ctx, cn, "pause")
+}
+
+func unpause(ctx *cli.Context) error {
+	if err := cn.Unpause(ctx.Context); err != nil {
 		return err
 	}
-	err = s.AddAirdrop(ctx.Context, owners, uint8(amount))
+	return finishAdmin(ctx, cn, "unpause")
+}
+
+func setPhase(ctx *cli.Context) error {
+	args := ctx.Args()
+	if args.Len() == 0 {
+		return errors.New("input phase 0-2")
+	}
+	phase_, err := strconv.ParseInt(args.Get(0), 0, 8)
+	if err != nil {
+		return errors.New("input phase should be 0-2")
+	}
+	newPhase := int8(phase_)
+	err = cn.SetPhase(ctx.Context, newPhase)
 	if err != nil {
 		return err
 	}
-	return nil
+	return finishAdmin(ctx, cn, "setPhase")
 }
 
-func addWhitelist(ctx *cli.Context) error {
-	amount := ctx.Int(amountFlag.Name)
-	addrFile := ctx.String(addressListFlag.Name)
-	f, err := os.Open(addrFile)
-	if err != nil {
-		return err
+// loadList loads the --addressList file with the strict list loader and prints
+// its issues. Commands which send refuse to go on past any issue without --force.
+// ENS names in the list all resolve at block, the latest block if nil.
+func loadList(ctx *cli.Context, block *big.Int, requireAmount, send bool) (*node.AddressList, error) {
+	file := ctx.String(addressListFlag.Name)
+	if file == "" {
+		return nil, errors.New("input the address list file with -f")
+	}
+	opts := node.ListOptions{Contract: cn.Contract(), RequireAmount: requireAmount}
+	names := 0
+	opts.Resolve = func(name string) (common.Address, error) {
+		if block == nil {
+			b, err := cn.PinBlock(ctx.Context, nil)
+			if err != nil {
+				return common.Address{}, err
+			}
+			block = b
+		}
+		names++
+		return cn.ResolveName(ctx.Context, name, block)
+	}
+	if ctx.IsSet(amountFlag.Name) {
+		amount := ctx.Int(amountFlag.Name)
+		if amount < 0 || amount > 255 {
+			return nil, fmt.Errorf("amount %d out of range 0-255", amount)
+		}
+		opts.DefaultAmount = uint8(amount)
 	}
-	defer f.Close()
-	r := csv.NewReader(f)
-	records, err := r.ReadAll()
+	list, err := node.LoadAddressList(file, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var owners []common.Address
-	for _, line := range records {
-		for _, token := range line {
-			owners = append(owners, common.HexToAddress(token))
+	if names > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %d ENS names resolved at block %s\n", file, names, block)
+	}
+	if len(list.Issues) > 0 {
+		for _, issue := range list.Issues {
+			_, _ = fmt.Fprintln(os.Stderr, issue)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %d accepted, %d rejected, %d warnings\n",
+			file, len(list.Entries), list.Rejected(), len(list.Issues)-list.Rejected())
+		if send && !ctx.Bool(forceFlag.Name) {
+			return nil, fmt.Errorf("%d issues in %s, fix the list or use --force to go on with the %d accepted addresses", len(list.Issues), file, len(list.Entries))
 		}
 	}
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err = hs.ParseJsonConfig(configFile, &cfg); err != nil {
-		return err
+	if len(list.Entries) == 0 {
+		return nil, fmt.Errorf("no valid address in %s", file)
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
-		return err
+	return list, nil
+}
+
+// parseOwner parses the --owner flag, an ENS name resolves at block.
+func parseOwner(ctx *cli.Context, block *big.Int) (common.Address, error) {
+	return parseAddress(ctx, "owner", ctx.String(OwnerFlag.Name), block)
+}
+
+// parseAddress parses a hex address, rejecting what HexToAddress would turn
+// into the zero address, or resolves an ENS name at block, the latest block
+// if nil.
+func parseAddress(ctx *cli.Context, what, s string, block *big.Int) (common.Address, error) {
+	if node.IsENSName(s) {
+		block, err := cn.PinBlock(ctx.Context, block)
+		if err != nil {
+			return common.Address{}, err
+		}
+		address, err := cn.ResolveName(ctx.Context, s, block)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("resolve %s %s: %w", what, s, err)
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "%s resolved to %s at block %s\n", s, address.Hex(), block)
+		return address, nil
 	}
-	err = s.AddWhitelist(ctx.Context, owners, uint8(amount))
-	if err != nil {
-		return err
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("bad %s address %q", what, s)
 	}
-	return nil
+	return common.HexToAddress(s), nil
 }
 
-func pause(ctx *cli.Context) error {
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
+// finishAdmin prints the dry run reports, or writes the collected Safe batch,
+// if the command ran in one of those modes.
+func finishAdmin(ctx *cli.Context, s *node.Node, name string) error {
+	if ctx.Bool(dryRunFlag.Name) {
+		printDryRun(s.DryRunReports())
+		return nil
+	}
+	safe := ctx.String(safeFlag.Name)
+	out := ctx.String(safeOutFlag.Name)
+	if safe == "" && out == "" {
+		return nil
+	}
+	var nonce *big.Int
+	if ctx.IsSet(safeNonceFlag.Name) {
+		nonce = new(big.Int).SetUint64(ctx.Uint64(safeNonceFlag.Name))
+	}
+	b, err := s.FinishSafeBatch(ctx.Context, nonce)
+	if err != nil {
 		return err
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
-		return err
+	if len(b.Calls) == 0 {
+		fmt.Println("Nothing to do")
+		return nil
 	}
-	if err := s.Pause(ctx.Context); err != nil {
-		return err
+	if out != "" {
+		if err = b.WriteTxBuilder(out, name); err != nil {
+			return err
+		}
+		fmt.Printf("Safe batch of %d calls written to %s\n", len(b.Calls), out)
+	}
+	if safe != "" {
+		tx, err := b.SafeTx()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("SafeTx to: %s, operation: %d, nonce: %s\n", tx.To.Hex(), tx.Operation, tx.Nonce)
+		fmt.Printf("SafeTx data: %s\n", hexutil.Encode(tx.Data))
+		fmt.Printf("SafeTx hash: %s\n", tx.Hash(b.ChainID, b.Safe).Hex())
 	}
 	return nil
 }
 
-func unpause(ctx *cli.Context) error {
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err := hs.ParseJsonConfig(configFile, &cfg); err != nil {
-		return err
+func printDryRun(reports []node.DryRunReport) {
+	if len(reports) == 0 {
+		fmt.Println("Nothing to do")
+		return
+	}
+	for _, r := range reports {
+		fmt.Printf("%s from %s at block %s\n", r.Method, r.From.Hex(), r.Block)
+		if !r.OK {
+			fmt.Printf("  REVERT: %s\n", r.Revert)
+			continue
+		}
+		fmt.Printf("  OK, gas %d, fee %s ETH at %s gwei\n", r.Gas, formatEther(r.Fee), formatGwei(r.GasPrice))
+		for _, d := range r.Diffs {
+			if d.Before == "" && d.After == "" {
+				fmt.Printf("  %s\n", d.Field)
+				continue
+			}
+			fmt.Printf("  %s: %s -> %s\n", d.Field, d.Before, d.After)
+		}
 	}
-	s := node.New(cfg)
-	if err := s.Init(ctx.Context); err != nil {
+}
+
+func auditQuota(ctx *cli.Context) error {
+	block, err := cn.PinBlock(ctx.Context, blockNumber(ctx))
+	if err != nil {
 		return err
 	}
-	if err := s.Unpause(ctx.Context); err != nil {
+	list, err := loadList(ctx, block, false, false)
+	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func setPhase(ctx *cli.Context) error {
-	args := ctx.Args()
-	if args.Len() == 0 {
-		return errors.New("input phase 0-2")
+	owners := list.Addresses()
+	var rejected []string
+	for _, issue := range list.Issues {
+		if issue.Rejected {
+			rejected = append(rejected, issue.Value)
+		}
 	}
-	phase_, err := strconv.ParseInt(args.Get(0), 0, 8)
+	audit, err := cn.AuditQuota(ctx.Context, ctx.String(kindFlag.Name), owners, block)
 	if err != nil {
-		return errors.New("input phase should be 0-2")
-	}
-	newPhase := int8(phase_)
-	configFile := ctx.String(ConfigFlag.Name)
-	cfg := node.Config{}
-	if err = hs.ParseJsonConfig(configFile, &cfg); err != nil {
 		return err
 	}
-	s := node.New(cfg)
-	if err = s.Init(ctx.Context); err != nil {
+
+	out := os.Stdout
+	if file := ctx.String(outputFlag.Name); file != "" {
+		if out, err = os.Create(file); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	switch ctx.String(reportFormatFlag.Name) {
+	case "json":
+		b, err := json.MarshalIndent(struct {
+			*node.QuotaAudit
+			Rejected []string `json:"rejected,omitempty"`
+		}{audit, rejected}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
 		return err
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"address", "minted", "cap", "status"})
+		for _, e := range audit.Entries {
+			_ = w.Write([]string{e.Address.Hex(), strconv.Itoa(int(e.Minted)), strconv.Itoa(int(e.Cap)), e.Status})
+		}
+		for _, token := range rejected {
+			_ = w.Write([]string{token, "", "", "rejected"})
+		}
+		w.Flush()
+		return w.Error()
+	case "table":
+	default:
+		return fmt.Errorf("unknown format %s", ctx.String(reportFormatFlag.Name))
 	}
-	err = s.SetPhase(ctx.Context, newPhase)
-	if err != nil {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Address\tMinted\tCap\tStatus")
+	for _, e := range audit.Entries {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", e.Address.Hex(), e.Minted, e.Cap, e.Status)
+	}
+	for _, token := range rejected {
+		_, _ = fmt.Fprintf(w, "%s\t\t\trejected\n", token)
+	}
+	if err = w.Flush(); err != nil {
 		return err
 	}
+	t := audit.Totals
+	_, _ = fmt.Fprintf(out, "\n%s quota at block %s: %d addresses, %d not added, %d unused, %d partial, %d fully used, minted %d of %d\n",
+		audit.Kind, audit.Block, t.Addresses, t.NotAdded, t.Unused, t.Partial, t.Used, t.Minted, t.Cap)
+	if len(rejected) > 0 {
+		_, _ = fmt.Fprintf(out, "%d rejected entries: %s\n", len(rejected), strings.Join(rejected, ", "))
+	}
 	return nil
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/urfave/cli/v2"
+	"io/ioutil"
+	"strings"
+)
+
+var txCommand = &cli.Command{
+	Name:  "tx",
+	Usage: "Offline signing and broadcast of prepared transactions",
+	Subcommands: []*cli.Command{
+		{
+			Action: signTx,
+			Name:   "sign",
+			Usage:  "sign an unsigned transaction file, with the mnemonic or a keystore, no network needed",
+			Flags: []cli.Flag{
+				inputFlag,
+				outputFlag,
+				keystoreFlag,
+				passwordFlag,
+				accountFlag,
+			},
+		},
+		{
+			Before: readOnlyNode,
+			Action: broadcastTx,
+			Name:   "broadcast",
+			Usage:  "send a raw signed transaction and wait for the receipt",
+			Flags: []cli.Flag{
+				inputFlag,
+			},
+		},
+		{
+			Before:    signingNode,
+			Action:    speedUpTx,
+			Name:      "speedup",
+			Usage:     "resend a pending transaction with the same nonce and bumped fees",
+			ArgsUsage: "<hash>",
+			Flags: []cli.Flag{
+				accountFlag,
+			},
+		},
+		{
+			Before:    signingNode,
+			Action:    cancelTx,
+			Name:      "cancel",
+			Usage:     "replace a pending transaction with a 0-value self transfer",
+			ArgsUsage: "<hash>",
+			Flags: []cli.Flag{
+				accountFlag,
+			},
+		},
+	},
+}
+
+func signTx(ctx *cli.Context) error {
+	u, err := node.ReadUnsignedTx(ctx.String(inputFlag.Name))
+	if err != nil {
+		return err
+	}
+	var signer node.Signer
+	if keyfile := ctx.String(keystoreFlag.Name); keyfile != "" {
+		passwordFile := ctx.String(passwordFlag.Name)
+		if passwordFile == "" {
+			return errors.New("keystore needs --password file")
+		}
+		b, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return err
+		}
+		signer, err = node.NewKeystoreSigner(keyfile, strings.TrimRight(string(b), "\r\n"))
+		if err != nil {
+			return err
+		}
+	} else {
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return err
+		}
+		signer, err = node.NewMnemonicSigner(cfg.Mnemonic, cfg.DerivationPath, cfg.Account)
+		if err != nil {
+			return err
+		}
+	}
+	tx, err := u.Sign(signer)
+	if err != nil {
+		return err
+	}
+	if out := ctx.String(outputFlag.Name); out != "" {
+		if err = node.WriteSignedTx(out, tx); err != nil {
+			return err
+		}
+		fmt.Printf("signed tx %s written to %s\n", tx.Hash(), out)
+		return nil
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	fmt.Println(hexutil.Encode(raw))
+	return nil
+}
+
+func broadcastTx(ctx *cli.Context) error {
+	cn.SetProgress(progressPrinter())
+	tx, err := node.ReadSignedTx(ctx.String(inputFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err = cn.Broadcast(ctx.Context, tx); err != nil {
+		return err
+	}
+	fmt.Printf("Mined: %s\n", tx.Hash())
+	return nil
+}
+
+func speedUpTx(ctx *cli.Context) error {
+	return replaceTx(ctx, false)
+}
+
+func cancelTx(ctx *cli.Context) error {
+	return replaceTx(ctx, true)
+}
+
+func replaceTx(ctx *cli.Context, cancel bool) error {
+	if ctx.Args().Len() == 0 {
+		return errors.New("input tx hash")
+	}
+	b, err := hexutil.Decode(ctx.Args().First())
+	if err != nil || len(b) != common.HashLength {
+		return errors.New("bad tx hash")
+	}
+	hash := common.BytesToHash(b)
+	var tx *types.Transaction
+	if cancel {
+		tx, err = cn.Cancel(ctx.Context, hash)
+	} else {
+		tx, err = cn.SpeedUp(ctx.Context, hash)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replaced by: %s\n", tx.Hash())
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+)
+
+var walletCommand = &cli.Command{
+	Name:  "wallet",
+	Usage: "Accounts derived from the configured mnemonic",
+	Subcommands: []*cli.Command{
+		{
+			Before: readOnlyNode,
+			Action: listWallet,
+			Name:   "list",
+			Usage:  "print derived addresses and balances",
+			Flags: []cli.Flag{
+				countFlag,
+				derivationPathFlag,
+			},
+		},
+	},
+}
+
+func listWallet(ctx *cli.Context) error {
+	cfg := cn.Config()
+	template := cfg.DerivationPath
+	if ctx.IsSet(derivationPathFlag.Name) {
+		template = ctx.String(derivationPathFlag.Name)
+	}
+	list, err := node.DeriveAccounts(cfg.Mnemonic, template, ctx.Int(countFlag.Name))
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		balance, err := cn.Balance(ctx.Context, a.Address)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d\t%s\t%s\t%s ETH\n", a.Index, a.Path, a.Address.Hex(), formatEther(balance))
+	}
+	return nil
+}
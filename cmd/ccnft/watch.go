@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/cybercar-nft/go-cybercar/node"
+	"github.com/urfave/cli/v2"
+)
+
+var watchCommand = &cli.Command{
+	Before: readOnlyNode,
+	Action: watch,
+	Name:   "watch",
+	Usage:  "watch the contract events and notify pauses, ownership transfers and transfer bursts per the notify config",
+	Flags: []cli.Flag{
+		fromBlockFlag,
+		intervalFlag,
+		watchConfirmationsFlag,
+	},
+}
+
+func watch(ctx *cli.Context) error {
+	opts := node.WatchOptions{
+		From:          ctx.Uint64(fromBlockFlag.Name),
+		Confirmations: ctx.Uint64(watchConfirmationsFlag.Name),
+		Interval:      ctx.Duration(intervalFlag.Name),
+	}
+	err := cn.WatchNotify(ctx.Context, opts)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
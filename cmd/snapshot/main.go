@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/cybercar-nft/go-cybercar/node"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -23,6 +24,12 @@ func init() {
 		Usage:   "CyberCar NFT CLI",
 		Version: "0.1.0",
 		Action:  ownerOf,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "reverse",
+				Usage: "add the primary ENS name of every owner, looked up at the same block",
+			},
+		},
 	}
 }
 
@@ -63,6 +70,10 @@ func ownerOf(c *cli.Context) error {
 	opts := &bind.CallOpts{
 		BlockNumber: blockNumber,
 	}
+	var ens *node.ENS
+	if c.Bool("reverse") {
+		ens = node.NewENS(node.ENSRegistry, ec)
+	}
 	total, err := nft.TotalSupply(opts)
 	if err != nil {
 		return err
@@ -76,7 +87,15 @@ func ownerOf(c *cli.Context) error {
 			//log.Printf("Error: %s", err)
 			continue
 		}
-		fmt.Printf("%d,%s\n", i, owner)
+		if ens == nil {
+			fmt.Printf("%d,%s\n", i, owner)
+			continue
+		}
+		name, err := ens.Lookup(c.Context, owner, blockNumber)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d,%s,%s\n", i, owner, name)
 	}
 	return nil
 }
@@ -0,0 +1,83 @@
+// Package cybertest deploys the CyberCar contract on a simulated backend, so
+// the node package can be tested end to end in-process.
+//
+// The tests needing the contract deploy the compiled artifact at
+// testdata/Car.json, or the one named by CYBERCAR_ARTIFACT, and are skipped
+// when there is none. The contract sources aren't part of this repository,
+// the artifact has to be copied from the contract's build.
+package cybertest
+
+import (
+	"errors"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ArtifactEnv names the artifact of the contract.
+const ArtifactEnv = "CYBERCAR_ARTIFACT"
+
+// ErrNoArtifact is returned by Deploy when there is no artifact.
+var ErrNoArtifact = errors.New("no contract artifact in testdata and " + ArtifactEnv + " not set")
+
+// Artifact returns the artifact named by ArtifactEnv, else testdata/Car.json
+// of this package if it exists, else "".
+func Artifact() string {
+	if file := os.Getenv(ArtifactEnv); file != "" {
+		return file
+	}
+	_, self, _, ok := runtime.Caller(0)
+	if !ok {
+		return ""
+	}
+	file := filepath.Join(filepath.Dir(self), "testdata", "Car.json")
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	return file
+}
+
+// Skip skips t when there is no artifact.
+func Skip(t testing.TB) {
+	t.Helper()
+	if Artifact() == "" {
+		t.Skip(ErrNoArtifact)
+	}
+}
+
+// Deploy deploys the contract of the artifact from auth and commits. It is not
+// initialized yet.
+func Deploy(auth *bind.TransactOpts, backend *backends.SimulatedBackend) (common.Address, *cyber.Car, error) {
+	file := Artifact()
+	if file == "" {
+		return common.Address{}, nil, ErrNoArtifact
+	}
+	artifact, err := cyber.ReadArtifact(file)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if err = artifact.CheckABI(); err != nil {
+		return common.Address{}, nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(cyber.CarABI))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	address, _, _, err := bind.DeployContract(auth, parsed, artifact.Bytecode, backend)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	car, err := cyber.NewCar(address, backend)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	backend.Commit()
+	return address, car, nil
+}
@@ -0,0 +1,91 @@
+package cyber
+
+//go:generate go run gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Artifact is a compiled contract as written by hardhat, truffle or foundry.
+type Artifact struct {
+	ABI      abi.ABI
+	Bytecode []byte
+}
+
+// ReadArtifact reads the abi and the creation bytecode from a hardhat or
+// truffle artifact ("bytecode": "0x..."), or a foundry one ("bytecode":
+// {"object": "0x..."}).
+func ReadArtifact(filename string) (*Artifact, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		ABI      json.RawMessage `json:"abi"`
+		Bytecode json.RawMessage `json:"bytecode"`
+	}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(raw.ABI) == 0 || len(raw.Bytecode) == 0 {
+		return nil, fmt.Errorf("%s: abi or bytecode missing, not a contract artifact", filename)
+	}
+	var code string
+	if err = json.Unmarshal(raw.Bytecode, &code); err != nil {
+		var foundry struct {
+			Object string `json:"object"`
+		}
+		if err = json.Unmarshal(raw.Bytecode, &foundry); err != nil {
+			return nil, fmt.Errorf("%s: bad bytecode: %w", filename, err)
+		}
+		code = foundry.Object
+	}
+	if strings.Contains(code, "__") {
+		return nil, fmt.Errorf("%s: bytecode has unlinked library placeholders", filename)
+	}
+	if !strings.HasPrefix(code, "0x") {
+		code = "0x" + code
+	}
+	a := &Artifact{}
+	if a.Bytecode, err = hexutil.Decode(code); err != nil {
+		return nil, fmt.Errorf("%s: bad bytecode: %w", filename, err)
+	}
+	if len(a.Bytecode) == 0 {
+		return nil, fmt.Errorf("%s: empty bytecode, an interface or abstract contract", filename)
+	}
+	if a.ABI, err = abi.JSON(strings.NewReader(string(raw.ABI))); err != nil {
+		return nil, fmt.Errorf("%s: bad abi: %w", filename, err)
+	}
+	return a, nil
+}
+
+// CheckABI makes sure the artifact has every method and event of the binding,
+// so it is the contract the rest of the CLI talks to.
+func (a *Artifact) CheckABI() error {
+	parsed, err := abi.JSON(strings.NewReader(CarABI))
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, m := range parsed.Methods {
+		if got, ok := a.ABI.Methods[m.Name]; !ok || got.Sig != m.Sig || len(got.Outputs) != len(m.Outputs) {
+			missing = append(missing, m.Sig)
+		}
+	}
+	for _, e := range parsed.Events {
+		if got, ok := a.ABI.Events[e.Name]; !ok || got.ID != e.ID {
+			missing = append(missing, "event "+e.Sig)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("artifact doesn't match the Car binding, missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build ignore
+// +build ignore
+
+// Gen regenerates the Car binding, with its bytecode, from the compiled
+// contract artifact of the tests, cybertest/testdata/Car.json or the one named
+// by CYBERCAR_ARTIFACT:
+//
+//	go generate ./cyber
+//
+// car.go is left as is when there is none.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/cybercar-nft/go-cybercar/cyber/cybertest"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	file := cybertest.Artifact()
+	if file == "" {
+		fmt.Fprintf(os.Stderr, "%s, car.go left as is\n", cybertest.ErrNoArtifact)
+		return
+	}
+	if err := gen(file, "car.go"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func gen(file, out string) error {
+	artifact, err := cyber.ReadArtifact(file)
+	if err != nil {
+		return err
+	}
+	// the CLI calls every method of the current binding
+	if err = artifact.CheckABI(); err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var raw struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	code, err := bind.Bind([]string{"Car"}, []string{string(raw.ABI)}, []string{hexutil.Encode(artifact.Bytecode)}, nil, "cyber", bind.LangGo, nil, nil)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, []byte(code), 0644)
+}
@@ -0,0 +1,506 @@
+package cyber
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Revert reasons of the OpenZeppelin contracts CyberCar builds on, as in
+// their 4.x source. They are also the rules of these reverts.
+const (
+	ReasonNotOwner    = "Ownable: caller is not the owner"
+	ReasonZeroOwner   = "Ownable: new owner is the zero address"
+	ReasonInitialized = "Initializable: contract is already initialized"
+	ReasonPaused      = "Pausable: paused"
+	ReasonNotPaused   = "Pausable: not paused"
+	ReasonNoToken     = "ERC721: owner query for nonexistent token"
+)
+
+// Sale rules of CyberCar the Mirror models. The contract source isn't part of
+// this repository, so the reasons it reverts them with are not known: their
+// RevertError names the rule and leaves Reason empty.
+const (
+	RuleZeroAmount       = "zero amount"
+	RuleNotStarted       = "sale not started"
+	RuleWrongValue       = "wrong value"
+	RuleExceedsQuota     = "exceeds quota"
+	RuleExceedsWhitelist = "exceeds whitelist cap"
+	RuleExceedsCapacity  = "exceeds capacity"
+	RuleNothingReserved  = "nothing reserved"
+	RuleNotPayable       = "not payable"
+	RuleUnknownMethod    = "unknown method"
+)
+
+var knownReasons = map[string]bool{
+	ReasonNotOwner:    true,
+	ReasonZeroOwner:   true,
+	ReasonInitialized: true,
+	ReasonPaused:      true,
+	ReasonNotPaused:   true,
+	ReasonNoToken:     true,
+}
+
+// Phases of the sale. Claim and mint are closed before PhaseWhitelist; in
+// PhaseWhitelist only whitelisted addresses mint, up to their quota and the
+// whitelist cap of the collection; from PhasePublic anyone mints.
+const (
+	PhaseClosed    int8 = 0
+	PhaseWhitelist int8 = 1
+	PhasePublic    int8 = 2
+)
+
+// RevertError is a call the contract would revert, with the rule it breaks
+// and its revert reason, if known.
+type RevertError struct {
+	Method string
+	Rule   string
+	Reason string
+}
+
+func reverted(method, rule string) *RevertError {
+	e := &RevertError{Method: method, Rule: rule}
+	if knownReasons[rule] {
+		e.Reason = rule
+	}
+	return e
+}
+
+func (e *RevertError) Error() string {
+	return fmt.Sprintf("%s reverted: %s", e.Method, e.Rule)
+}
+
+// Mirror is an in-memory model of the observable rules of the contract, to
+// play through a campaign of phases, quotas, capacity and reserve without a
+// chain. It is driven like CarTransactor: the same method names, with the
+// sender and the value taken from the TransactOpts. A call either applies
+// completely or returns a *RevertError and changes nothing.
+//
+// Only the OpenZeppelin rules are known. The sale rules are assumptions drawn
+// from the ABI, each marked "Assumption" below. None of them has been checked
+// against the contract: mirror_test.go compares both step by step, but needs
+// the compiled artifact, which isn't in this repository.
+type Mirror struct {
+	capacity     uint64
+	whitelistCap uint64
+	mintPrice    *big.Int
+
+	owner       common.Address
+	initialized bool
+	paused      bool
+	phase       int8
+	balance     *big.Int
+	whitelist   map[common.Address]*Quota
+	airdrop     map[common.Address]*Quota
+	reserve     map[common.Address]uint8
+	tokens      []common.Address // owner of token id i+1
+	balances    map[common.Address]uint64
+}
+
+// Quota is what an address minted of its cap, as mintQuota and airdropQuota return.
+type Quota struct {
+	Minted uint8
+	Cap    uint8
+}
+
+// NewMirror returns an undeployed-like contract, not initialized, with the
+// collection constants.
+func NewMirror(capacity, whitelistCap uint64, mintPrice *big.Int) *Mirror {
+	return &Mirror{
+		capacity:     capacity,
+		whitelistCap: whitelistCap,
+		mintPrice:    new(big.Int).Set(mintPrice),
+		balance:      new(big.Int),
+		whitelist:    make(map[common.Address]*Quota),
+		airdrop:      make(map[common.Address]*Quota),
+		reserve:      make(map[common.Address]uint8),
+		balances:     make(map[common.Address]uint64),
+	}
+}
+
+// Transact calls method by its contract name, e.g. "addWhitelist", as
+// CarRaw.Transact does.
+func (m *Mirror) Transact(opts *bind.TransactOpts, method string, params ...interface{}) error {
+	// Assumption: mint is the only payable method.
+	if method != "mint" && opts.Value != nil && opts.Value.Sign() > 0 {
+		return reverted(method, RuleNotPayable)
+	}
+	bad := func() error {
+		return fmt.Errorf("%s: bad arguments %v", method, params)
+	}
+	switch method {
+	case "initialize":
+		return m.Initialize(opts)
+	case "transferOwnership":
+		if len(params) != 1 {
+			return bad()
+		}
+		to, ok := params[0].(common.Address)
+		if !ok {
+			return bad()
+		}
+		return m.TransferOwnership(opts, to)
+	case "renounceOwnership":
+		return m.RenounceOwnership(opts)
+	case "pause":
+		return m.Pause(opts)
+	case "unpause":
+		return m.Unpause(opts)
+	case "setPhase":
+		if len(params) != 1 {
+			return bad()
+		}
+		phase, ok := params[0].(int8)
+		if !ok {
+			return bad()
+		}
+		return m.SetPhase(opts, phase)
+	case "addWhitelist", "addAirdrop", "addReserve":
+		if len(params) != 2 {
+			return bad()
+		}
+		addrs, ok1 := params[0].([]common.Address)
+		amount, ok2 := params[1].(uint8)
+		if !ok1 || !ok2 {
+			return bad()
+		}
+		add := map[string]func(*bind.TransactOpts, []common.Address, uint8) error{
+			"addWhitelist": m.AddWhitelist,
+			"addAirdrop":   m.AddAirdrop,
+			"addReserve":   m.AddReserve,
+		}[method]
+		return add(opts, addrs, amount)
+	case "claim", "mint":
+		if len(params) != 1 {
+			return bad()
+		}
+		amount, ok := params[0].(uint8)
+		if !ok {
+			return bad()
+		}
+		if method == "claim" {
+			return m.Claim(opts, amount)
+		}
+		return m.Mint(opts, amount)
+	case "reserve":
+		return m.Reserve(opts)
+	case "withdraw":
+		return m.Withdraw(opts)
+	}
+	return reverted(method, RuleUnknownMethod)
+}
+
+func (m *Mirror) onlyOwner(opts *bind.TransactOpts, method string) error {
+	if opts.From != m.owner {
+		return reverted(method, ReasonNotOwner)
+	}
+	return nil
+}
+
+func (m *Mirror) whenNotPaused(method string) error {
+	if m.paused {
+		return reverted(method, ReasonPaused)
+	}
+	return nil
+}
+
+// Initialize makes the sender the owner, once.
+func (m *Mirror) Initialize(opts *bind.TransactOpts) error {
+	if m.initialized {
+		return reverted("initialize", ReasonInitialized)
+	}
+	m.initialized = true
+	m.owner = opts.From
+	return nil
+}
+
+func (m *Mirror) TransferOwnership(opts *bind.TransactOpts, newOwner common.Address) error {
+	if err := m.onlyOwner(opts, "transferOwnership"); err != nil {
+		return err
+	}
+	if newOwner == (common.Address{}) {
+		return reverted("transferOwnership", ReasonZeroOwner)
+	}
+	m.owner = newOwner
+	return nil
+}
+
+func (m *Mirror) RenounceOwnership(opts *bind.TransactOpts) error {
+	if err := m.onlyOwner(opts, "renounceOwnership"); err != nil {
+		return err
+	}
+	m.owner = common.Address{}
+	return nil
+}
+
+func (m *Mirror) Pause(opts *bind.TransactOpts) error {
+	if err := m.onlyOwner(opts, "pause"); err != nil {
+		return err
+	}
+	if err := m.whenNotPaused("pause"); err != nil {
+		return err
+	}
+	m.paused = true
+	return nil
+}
+
+func (m *Mirror) Unpause(opts *bind.TransactOpts) error {
+	if err := m.onlyOwner(opts, "unpause"); err != nil {
+		return err
+	}
+	if !m.paused {
+		return reverted("unpause", ReasonNotPaused)
+	}
+	m.paused = false
+	return nil
+}
+
+func (m *Mirror) SetPhase(opts *bind.TransactOpts, newPhase int8) error {
+	if err := m.onlyOwner(opts, "setPhase"); err != nil {
+		return err
+	}
+	// Assumption: any phase is accepted, backwards too, paused or not.
+	m.phase = newPhase
+	return nil
+}
+
+// AddWhitelist sets the whitelist cap of every address to amount, keeping
+// what they minted. Assumption: the cap is set, not added to, and may go
+// below what was minted.
+func (m *Mirror) AddWhitelist(opts *bind.TransactOpts, addrs []common.Address, amount uint8) error {
+	if err := m.onlyOwner(opts, "addWhitelist"); err != nil {
+		return err
+	}
+	setCaps(m.whitelist, addrs, amount)
+	return nil
+}
+
+// AddAirdrop sets the airdrop cap of every address to amount, keeping what
+// they claimed. Assumption: as AddWhitelist.
+func (m *Mirror) AddAirdrop(opts *bind.TransactOpts, addrs []common.Address, amount uint8) error {
+	if err := m.onlyOwner(opts, "addAirdrop"); err != nil {
+		return err
+	}
+	setCaps(m.airdrop, addrs, amount)
+	return nil
+}
+
+// AddReserve sets the reserve of every address to amount. Assumption: set,
+// not added to, and not checked against the capacity.
+func (m *Mirror) AddReserve(opts *bind.TransactOpts, addrs []common.Address, amount uint8) error {
+	if err := m.onlyOwner(opts, "addReserve"); err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		m.reserve[a] = amount
+	}
+	return nil
+}
+
+func setCaps(quotas map[common.Address]*Quota, addrs []common.Address, amount uint8) {
+	for _, a := range addrs {
+		q, ok := quotas[a]
+		if !ok {
+			q = &Quota{}
+			quotas[a] = q
+		}
+		q.Cap = amount
+	}
+}
+
+// started checks the sale is open, for claim and mint. Assumption: paused
+// first, then a zero amount, then a phase below PhaseWhitelist, so claim
+// opens with the whitelist phase and not before.
+func (m *Mirror) started(method string, amount uint8) error {
+	if err := m.whenNotPaused(method); err != nil {
+		return err
+	}
+	if amount == 0 {
+		return reverted(method, RuleZeroAmount)
+	}
+	if m.phase < PhaseWhitelist {
+		return reverted(method, RuleNotStarted)
+	}
+	return nil
+}
+
+// within checks q has room for amount more.
+func within(q *Quota, amount uint8) bool {
+	return q != nil && uint(q.Minted)+uint(amount) <= uint(q.Cap)
+}
+
+// Claim mints amount of the sender's airdrop quota. Assumption: the quota is
+// checked before the capacity, and claim is free in every phase.
+func (m *Mirror) Claim(opts *bind.TransactOpts, amount uint8) error {
+	if err := m.started("claim", amount); err != nil {
+		return err
+	}
+	q := m.airdrop[opts.From]
+	if !within(q, amount) {
+		return reverted("claim", RuleExceedsQuota)
+	}
+	if err := m.checkCapacity("claim", uint64(amount)); err != nil {
+		return err
+	}
+	q.Minted += amount
+	m.mintTo(opts.From, uint64(amount))
+	return nil
+}
+
+// Mint sells amount tokens for mintPrice each. In the whitelist phase they
+// count against the sender's whitelist quota and the whitelist cap.
+//
+// Assumptions: the value must equal the price exactly, overpaying reverts
+// too; the whitelist cap bounds the total supply, claims and reserve
+// included, not only the whitelist mints; the checks run in the order value,
+// quota, whitelist cap, capacity; the public phase has no per-address limit.
+func (m *Mirror) Mint(opts *bind.TransactOpts, amount uint8) error {
+	if err := m.started("mint", amount); err != nil {
+		return err
+	}
+	value := opts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	price := new(big.Int).Mul(m.mintPrice, big.NewInt(int64(amount)))
+	if value.Cmp(price) != 0 {
+		return reverted("mint", RuleWrongValue)
+	}
+	var q *Quota
+	if m.phase == PhaseWhitelist {
+		q = m.whitelist[opts.From]
+		if !within(q, amount) {
+			return reverted("mint", RuleExceedsQuota)
+		}
+		if m.TotalSupply()+uint64(amount) > m.whitelistCap {
+			return reverted("mint", RuleExceedsWhitelist)
+		}
+	}
+	if err := m.checkCapacity("mint", uint64(amount)); err != nil {
+		return err
+	}
+	if q != nil {
+		q.Minted += amount
+	}
+	m.balance.Add(m.balance, value)
+	m.mintTo(opts.From, uint64(amount))
+	return nil
+}
+
+// Reserve mints the sender's whole reserve. Assumption: in any phase, even
+// PhaseClosed, and all of it at once.
+func (m *Mirror) Reserve(opts *bind.TransactOpts) error {
+	if err := m.whenNotPaused("reserve"); err != nil {
+		return err
+	}
+	amount := uint64(m.reserve[opts.From])
+	if amount == 0 {
+		return reverted("reserve", RuleNothingReserved)
+	}
+	if err := m.checkCapacity("reserve", amount); err != nil {
+		return err
+	}
+	m.reserve[opts.From] = 0
+	m.mintTo(opts.From, amount)
+	return nil
+}
+
+// Withdraw sends the sale proceeds to the owner. Assumption: the whole
+// balance, paused or not.
+func (m *Mirror) Withdraw(opts *bind.TransactOpts) error {
+	if err := m.onlyOwner(opts, "withdraw"); err != nil {
+		return err
+	}
+	m.balance.SetUint64(0)
+	return nil
+}
+
+func (m *Mirror) checkCapacity(method string, amount uint64) error {
+	if m.TotalSupply()+amount > m.capacity {
+		return reverted(method, RuleExceedsCapacity)
+	}
+	return nil
+}
+
+// mintTo mints the next amount token ids. Assumption: they start at 1.
+func (m *Mirror) mintTo(to common.Address, amount uint64) {
+	for i := uint64(0); i < amount; i++ {
+		m.tokens = append(m.tokens, to)
+	}
+	m.balances[to] += amount
+}
+
+func (m *Mirror) Owner() common.Address {
+	return m.owner
+}
+
+func (m *Mirror) Paused() bool {
+	return m.paused
+}
+
+func (m *Mirror) Phase() int8 {
+	return m.phase
+}
+
+func (m *Mirror) Capacity() uint64 {
+	return m.capacity
+}
+
+func (m *Mirror) WhitelistCap() uint64 {
+	return m.whitelistCap
+}
+
+func (m *Mirror) MintPrice() *big.Int {
+	return new(big.Int).Set(m.mintPrice)
+}
+
+func (m *Mirror) TotalSupply() uint64 {
+	return uint64(len(m.tokens))
+}
+
+// Reserved is the reserve quota left, the tokens addReserve set aside and
+// Reserve didn't mint yet. Assumption: that is what the contract's
+// reserved() returns.
+func (m *Mirror) Reserved() uint64 {
+	var reserved uint64
+	for _, amount := range m.reserve {
+		reserved += uint64(amount)
+	}
+	return reserved
+}
+
+// Balance is the ether the contract holds.
+func (m *Mirror) Balance() *big.Int {
+	return new(big.Int).Set(m.balance)
+}
+
+func (m *Mirror) MintQuota(addr common.Address) Quota {
+	if q, ok := m.whitelist[addr]; ok {
+		return *q
+	}
+	return Quota{}
+}
+
+func (m *Mirror) AirdropQuota(addr common.Address) Quota {
+	if q, ok := m.airdrop[addr]; ok {
+		return *q
+	}
+	return Quota{}
+}
+
+// ReserveOf is the reserve left to addr, the contract has no getter for it.
+func (m *Mirror) ReserveOf(addr common.Address) uint8 {
+	return m.reserve[addr]
+}
+
+func (m *Mirror) BalanceOf(owner common.Address) uint64 {
+	return m.balances[owner]
+}
+
+func (m *Mirror) OwnerOf(tokenId uint64) (common.Address, error) {
+	if tokenId == 0 || tokenId > uint64(len(m.tokens)) {
+		return common.Address{}, reverted("ownerOf", ReasonNoToken)
+	}
+	return m.tokens[tokenId-1], nil
+}
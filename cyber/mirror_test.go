@@ -0,0 +1,380 @@
+package cyber_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/cybercar-nft/go-cybercar/cyber/cybertest"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testChainID = 1337
+
+func TestMirrorCampaign(t *testing.T) {
+	owner, alice, bob := common.HexToAddress("0x01"), common.HexToAddress("0x0a"), common.HexToAddress("0x0b")
+	from := func(a common.Address, value int64) *bind.TransactOpts {
+		return &bind.TransactOpts{From: a, Value: new(big.Int).Mul(big.NewInt(value), big.NewInt(1e16))}
+	}
+	m := cyber.NewMirror(10, 4, big.NewInt(1e16))
+	steps := []struct {
+		opts   *bind.TransactOpts
+		method string
+		params []interface{}
+		rule   string
+		ok     bool
+	}{
+		{from(owner, 0), "initialize", nil, "", true},
+		{from(alice, 0), "initialize", nil, cyber.ReasonInitialized, false},
+		{from(alice, 0), "addWhitelist", []interface{}{[]common.Address{alice}, uint8(3)}, cyber.ReasonNotOwner, false},
+		{from(owner, 0), "addWhitelist", []interface{}{[]common.Address{alice, bob}, uint8(3)}, "", true},
+		{from(owner, 0), "addAirdrop", []interface{}{[]common.Address{bob}, uint8(2)}, "", true},
+		{from(owner, 0), "addReserve", []interface{}{[]common.Address{owner}, uint8(5)}, "", true},
+		{from(alice, 1), "mint", []interface{}{uint8(1)}, cyber.RuleNotStarted, false},
+		{from(bob, 0), "claim", []interface{}{uint8(1)}, cyber.RuleNotStarted, false},
+		{from(owner, 0), "setPhase", []interface{}{cyber.PhaseWhitelist}, "", true},
+		{from(alice, 1), "mint", []interface{}{uint8(2)}, cyber.RuleWrongValue, false},
+		{from(alice, 0), "mint", []interface{}{uint8(0)}, cyber.RuleZeroAmount, false},
+		{from(alice, 3), "mint", []interface{}{uint8(3)}, "", true},
+		{from(alice, 1), "mint", []interface{}{uint8(1)}, cyber.RuleExceedsQuota, false},
+		{from(bob, 2), "mint", []interface{}{uint8(2)}, cyber.RuleExceedsWhitelist, false},
+		{from(bob, 1), "mint", []interface{}{uint8(1)}, "", true},
+		{from(bob, 0), "claim", []interface{}{uint8(2)}, "", true},
+		{from(owner, 0), "pause", nil, "", true},
+		{from(owner, 0), "reserve", nil, cyber.ReasonPaused, false},
+		{from(owner, 0), "unpause", nil, "", true},
+		{from(owner, 0), "reserve", nil, cyber.RuleExceedsCapacity, false},
+		{from(owner, 0), "setPhase", []interface{}{cyber.PhasePublic}, "", true},
+		{from(alice, 4), "mint", []interface{}{uint8(4)}, "", true},
+		{from(owner, 0), "reserve", nil, cyber.RuleExceedsCapacity, false},
+		{from(alice, 0), "withdraw", nil, cyber.ReasonNotOwner, false},
+		{from(owner, 0), "withdraw", nil, "", true},
+	}
+	for i, s := range steps {
+		err := m.Transact(s.opts, s.method, s.params...)
+		var re *cyber.RevertError
+		switch {
+		case s.ok && err != nil:
+			t.Fatalf("step %d %s: %v", i, s.method, err)
+		case !s.ok && (!errors.As(err, &re) || re.Rule != s.rule):
+			t.Fatalf("step %d %s: err = %v, want revert %q", i, s.method, err, s.rule)
+		}
+	}
+	if m.TotalSupply() != 10 || m.BalanceOf(alice) != 7 || m.BalanceOf(bob) != 3 || m.Reserved() != 5 {
+		t.Errorf("supply %d, alice %d, bob %d, reserved %d; want 10, 7, 3, 5", m.TotalSupply(), m.BalanceOf(alice), m.BalanceOf(bob), m.Reserved())
+	}
+	if q := m.MintQuota(alice); q.Minted != 3 || q.Cap != 3 {
+		t.Errorf("mintQuota of alice = %+v, public mints don't count", q)
+	}
+	if m.Balance().Sign() != 0 || m.ReserveOf(owner) != 5 {
+		t.Errorf("balance %s and reserve %d left after withdraw", m.Balance(), m.ReserveOf(owner))
+	}
+	if id, _ := m.OwnerOf(5); id != bob {
+		t.Errorf("owner of token 5 = %s, want bob", id.Hex())
+	}
+}
+
+// chain is the contract on a simulated chain, with the accounts driving it.
+type chain struct {
+	backend  *backends.SimulatedBackend
+	address  common.Address
+	car      *cyber.Car
+	abi      abi.ABI
+	keys     []*ecdsa.PrivateKey
+	accounts []common.Address
+}
+
+func newChain(t *testing.T, accounts int) *chain {
+	t.Helper()
+	cybertest.Skip(t)
+	c := &chain{}
+	alloc := core.GenesisAlloc{}
+	ether := new(big.Int).Lsh(big.NewInt(1), 100)
+	for i := 0; i < accounts; i++ {
+		key, _ := crypto.GenerateKey()
+		c.keys = append(c.keys, key)
+		c.accounts = append(c.accounts, crypto.PubkeyToAddress(key.PublicKey))
+		alloc[c.accounts[i]] = core.GenesisAccount{Balance: ether}
+	}
+	c.backend = backends.NewSimulatedBackend(alloc, 30_000_000)
+	t.Cleanup(func() { _ = c.backend.Close() })
+	var err error
+	if c.address, c.car, err = cybertest.Deploy(c.opts(t, 0, nil), c.backend); err != nil {
+		t.Fatal(err)
+	}
+	if c.abi, err = abi.JSON(strings.NewReader(cyber.CarABI)); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (c *chain) opts(t *testing.T, account int, value *big.Int) *bind.TransactOpts {
+	auth, err := bind.NewKeyedTransactorWithChainID(c.keys[account], big.NewInt(testChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Value = value
+	// reverting calls can't be estimated
+	auth.GasLimit = 1_000_000
+	return auth
+}
+
+// transact calls method first to learn the revert reason, then sends and mines
+// it and reports whether it reverted.
+func (c *chain) transact(opts *bind.TransactOpts, method string, params ...interface{}) (reverted bool, reason string, err error) {
+	ctx := context.Background()
+	data, err := c.abi.Pack(method, params...)
+	if err != nil {
+		return false, "", err
+	}
+	_, callErr := c.backend.CallContract(ctx, ethereum.CallMsg{From: opts.From, To: &c.address, Value: opts.Value, Data: data}, nil)
+	tx, err := (&cyber.CarRaw{Contract: c.car}).Transact(opts, method, params...)
+	if err != nil {
+		return false, "", err
+	}
+	c.backend.Commit()
+	receipt, err := c.backend.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return false, "", err
+	}
+	reverted = receipt.Status == types.ReceiptStatusFailed
+	if reverted != (callErr != nil) {
+		return false, "", fmt.Errorf("%s: call error %v, but receipt status %d", method, callErr, receipt.Status)
+	}
+	return reverted, revertReason(callErr), nil
+}
+
+func revertReason(err error) string {
+	var de rpc.DataError
+	if errors.As(err, &de) {
+		if s, ok := de.ErrorData().(string); ok {
+			if data, e := hexutil.Decode(s); e == nil {
+				if reason, e := abi.UnpackRevert(data); e == nil {
+					return reason
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// state is what both sides expose, for the accounts of the test.
+type state struct {
+	Owner       common.Address
+	Paused      bool
+	Phase       int8
+	TotalSupply uint64
+	Reserved    uint64
+	Balance     string
+	MintQuota   []cyber.Quota
+	Airdrop     []cyber.Quota
+	Tokens      []uint64
+	LastOwner   common.Address
+}
+
+func (c *chain) state(t *testing.T) state {
+	t.Helper()
+	opts := &bind.CallOpts{}
+	must := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var s state
+	var err error
+	s.Owner, err = c.car.Owner(opts)
+	must(err)
+	s.Paused, err = c.car.Paused(opts)
+	must(err)
+	s.Phase, err = c.car.Phase(opts)
+	must(err)
+	supply, err := c.car.TotalSupply(opts)
+	must(err)
+	s.TotalSupply = supply.Uint64()
+	reserved, err := c.car.Reserved(opts)
+	must(err)
+	s.Reserved = reserved.Uint64()
+	balance, err := c.backend.BalanceAt(context.Background(), c.address, nil)
+	must(err)
+	s.Balance = balance.String()
+	for _, a := range c.accounts {
+		mq, err := c.car.MintQuota(opts, a)
+		must(err)
+		s.MintQuota = append(s.MintQuota, cyber.Quota{Minted: mq.Minted, Cap: mq.Cap})
+		aq, err := c.car.AirdropQuota(opts, a)
+		must(err)
+		s.Airdrop = append(s.Airdrop, cyber.Quota{Minted: aq.Minted, Cap: aq.Cap})
+		tokens, err := c.car.BalanceOf(opts, a)
+		must(err)
+		s.Tokens = append(s.Tokens, tokens.Uint64())
+	}
+	if s.TotalSupply > 0 {
+		s.LastOwner, err = c.car.OwnerOf(opts, supply)
+		must(err)
+	}
+	return s
+}
+
+func mirrorState(m *cyber.Mirror, accounts []common.Address) state {
+	s := state{
+		Owner:       m.Owner(),
+		Paused:      m.Paused(),
+		Phase:       m.Phase(),
+		TotalSupply: m.TotalSupply(),
+		Reserved:    m.Reserved(),
+		Balance:     m.Balance().String(),
+	}
+	for _, a := range accounts {
+		s.MintQuota = append(s.MintQuota, m.MintQuota(a))
+		s.Airdrop = append(s.Airdrop, m.AirdropQuota(a))
+		s.Tokens = append(s.Tokens, m.BalanceOf(a))
+	}
+	if s.TotalSupply > 0 {
+		s.LastOwner, _ = m.OwnerOf(s.TotalSupply)
+	}
+	return s
+}
+
+// TestMirrorDifferential drives the mirror and the contract of the artifact
+// with the same random calls and compares the outcome, the revert reason
+// where the mirror knows it and the state after each of them.
+func TestMirrorDifferential(t *testing.T) {
+	const accounts, steps = 4, 500
+	c := newChain(t, accounts)
+	opts := &bind.CallOpts{}
+	capacity, err := c.car.Capacity(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	whitelistCap, err := c.car.WhitelistCap(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := c.car.MintPrice(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := cyber.NewMirror(capacity.Uint64(), whitelistCap.Uint64(), price)
+
+	r := rand.New(rand.NewSource(47))
+	some := func() []common.Address {
+		var list []common.Address
+		for _, a := range c.accounts {
+			if r.Intn(2) == 0 {
+				list = append(list, a)
+			}
+		}
+		return list
+	}
+	type call struct {
+		method string
+		params []interface{}
+		value  *big.Int
+	}
+	next := func() call {
+		amount := uint8(r.Intn(9))
+		switch r.Intn(12) {
+		case 0:
+			return call{method: "pause"}
+		case 1:
+			return call{method: "unpause"}
+		case 2:
+			return call{method: "setPhase", params: []interface{}{int8(r.Intn(5) - 1)}}
+		case 3:
+			return call{method: "addWhitelist", params: []interface{}{some(), amount}}
+		case 4:
+			return call{method: "addAirdrop", params: []interface{}{some(), amount}}
+		case 5:
+			return call{method: "addReserve", params: []interface{}{some(), amount}}
+		case 6:
+			return call{method: "reserve"}
+		case 7:
+			return call{method: "withdraw"}
+		case 8:
+			if r.Intn(4) == 0 {
+				to := c.accounts[r.Intn(accounts)]
+				if r.Intn(4) == 0 {
+					to = common.Address{}
+				}
+				return call{method: "transferOwnership", params: []interface{}{to}}
+			}
+			return call{method: "initialize"}
+		case 9:
+			return call{method: "claim", params: []interface{}{amount}}
+		default:
+			value := new(big.Int).Mul(price, big.NewInt(int64(amount)))
+			if r.Intn(5) == 0 {
+				value.Add(value, big.NewInt(int64(r.Intn(3)-1)))
+				if value.Sign() < 0 {
+					value.SetUint64(0)
+				}
+			}
+			return call{method: "mint", params: []interface{}{amount}, value: value}
+		}
+	}
+
+	seen := make(map[string]bool)
+	apply := func(step, account int, cl call) {
+		t.Helper()
+		auth := c.opts(t, account, cl.value)
+		reverted, reason, err := c.transact(auth, cl.method, cl.params...)
+		if err != nil {
+			t.Fatalf("step %d %s: %v", step, cl.method, err)
+		}
+		mirrorErr := m.Transact(auth, cl.method, cl.params...)
+		var re *cyber.RevertError
+		if mirrorErr != nil && !errors.As(mirrorErr, &re) {
+			t.Fatalf("step %d %s: %v", step, cl.method, mirrorErr)
+		}
+		if reverted != (re != nil) {
+			t.Fatalf("step %d %s%v from %d: contract reverted %v (%q), mirror: %v", step, cl.method, cl.params, account, reverted, reason, mirrorErr)
+		}
+		// the reasons of the sale rules are not known, only the OpenZeppelin ones
+		if re != nil && re.Reason != "" && re.Reason != reason {
+			t.Fatalf("step %d %s%v: contract reverted %q, mirror %q", step, cl.method, cl.params, reason, re.Reason)
+		}
+		if re != nil {
+			seen[re.Rule] = true
+		}
+		if got, want := c.state(t), mirrorState(m, c.accounts); !reflect.DeepEqual(got, want) {
+			t.Fatalf("step %d %s%v from %d: contract state\n%+v\nmirror state\n%+v", step, cl.method, cl.params, account, got, want)
+		}
+	}
+
+	apply(0, 0, call{method: "initialize"})
+	for step := 1; step <= steps; step++ {
+		cl := next()
+		// half of the calls from the owner, so admin calls go through
+		account := r.Intn(accounts)
+		if r.Intn(2) == 0 {
+			for i, a := range c.accounts {
+				if a == m.Owner() {
+					account = i
+				}
+			}
+		}
+		apply(step, account, cl)
+	}
+	// the run reached the limits it is meant to compare
+	for _, rule := range []string{cyber.RuleNotStarted, cyber.ReasonPaused, cyber.RuleWrongValue,
+		cyber.RuleExceedsQuota, cyber.RuleExceedsWhitelist, cyber.RuleExceedsCapacity, cyber.RuleNothingReserved} {
+		if !seen[rule] {
+			t.Errorf("no call broke %q in %d steps", rule, steps)
+		}
+	}
+}
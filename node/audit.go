@@ -0,0 +1,98 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Quota kinds of AuditQuota.
+const (
+	QuotaWhitelist = "whitelist"
+	QuotaAirdrop   = "airdrop"
+)
+
+// Quota states of an audited address.
+const (
+	QuotaNotAdded = "not-added"
+	QuotaUnused   = "unused"
+	QuotaPartial  = "partial"
+	QuotaUsed     = "used"
+)
+
+// QuotaEntry is the quota of one address.
+type QuotaEntry struct {
+	Address common.Address `json:"address"`
+	Minted  uint8          `json:"minted"`
+	Cap     uint8          `json:"cap"`
+	Status  string         `json:"status"`
+}
+
+// QuotaTotals sums up an audit.
+type QuotaTotals struct {
+	Addresses int `json:"addresses"`
+	NotAdded  int `json:"notAdded"`
+	Unused    int `json:"unused"`
+	Partial   int `json:"partial"`
+	Used      int `json:"used"`
+	Cap       int `json:"cap"`
+	Minted    int `json:"minted"`
+}
+
+// QuotaAudit is the quota of every address of a list, at one block.
+type QuotaAudit struct {
+	Kind    string       `json:"kind"`
+	Block   *big.Int     `json:"block"`
+	Entries []QuotaEntry `json:"entries"`
+	Totals  QuotaTotals  `json:"totals"`
+}
+
+// AuditQuota reads the quota of kind of every owner at block, the latest block
+// if nil. The owners are unique, as LoadAddressList returns them. There is no
+// reserve kind, the contract has no getter to read the reserve of an address.
+func (n *Node) AuditQuota(ctx context.Context, kind string, owners []common.Address, block *big.Int) (*QuotaAudit, error) {
+	quota := n.nft.MintQuota
+	switch kind {
+	case QuotaWhitelist:
+	case QuotaAirdrop:
+		quota = n.nft.AirdropQuota
+	default:
+		return nil, fmt.Errorf("unknown quota kind %s, use %s or %s", kind, QuotaWhitelist, QuotaAirdrop)
+	}
+	block, err := n.PinBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	audit := &QuotaAudit{Kind: kind, Block: block}
+	for _, owner := range owners {
+		q, err := quota(opts, owner)
+		if err != nil {
+			n.Sugar.Errorf("read %s quota of %s error: %s", kind, owner.Hex(), err)
+			return nil, err
+		}
+		e := QuotaEntry{Address: owner, Minted: q.Minted, Cap: q.Cap}
+		t := &audit.Totals
+		switch {
+		case q.Cap == 0:
+			e.Status = QuotaNotAdded
+			t.NotAdded++
+		case q.Minted == 0:
+			e.Status = QuotaUnused
+			t.Unused++
+		case q.Minted < q.Cap:
+			e.Status = QuotaPartial
+			t.Partial++
+		default:
+			e.Status = QuotaUsed
+			t.Used++
+		}
+		t.Addresses++
+		t.Cap += int(q.Cap)
+		t.Minted += int(q.Minted)
+		audit.Entries = append(audit.Entries, e)
+	}
+	return audit, nil
+}
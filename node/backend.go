@@ -0,0 +1,34 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Backend is what a Node needs from the chain. *ethclient.Client satisfies it,
+// and so does backends.SimulatedBackend, which the tests run on.
+type Backend interface {
+	bind.ContractBackend
+	WaitBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// chainIDReader is the part of ethclient the simulated backend lacks.
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// chainID asks the backend for its chain id, or takes Config.ChainID if the
+// backend can't tell, as the simulated one.
+func (n *Node) chainID(ctx context.Context) (*big.Int, error) {
+	if r, ok := n.backend.(chainIDReader); ok {
+		return r.ChainID(ctx)
+	}
+	if n.cfg.ChainID != 0 {
+		return new(big.Int).SetUint64(n.cfg.ChainID), nil
+	}
+	return nil, errors.New("the backend has no chain id, set chainId in the config")
+}
@@ -0,0 +1,115 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Deployment is a deployed and initialized contract.
+type Deployment struct {
+	Address common.Address `json:"address"`
+	Tx      common.Hash    `json:"tx"`
+	Block   uint64         `json:"block"`
+	ChainID uint64         `json:"chainId"`
+	Name    string         `json:"name"`
+	Symbol  string         `json:"symbol"`
+	Version string         `json:"version"`
+	Owner   common.Address `json:"owner"`
+}
+
+// Deploy deploys the artifact from the signer account, calls initialize and
+// checks the signer became the owner and the name and symbol are set. The node
+// is bound to the new contract from then on.
+func (n *Node) Deploy(ctx context.Context, artifact *cyber.Artifact) (*Deployment, error) {
+	if n.dryRun || n.safe != nil || n.unsignedOut != "" {
+		return nil, errors.New("deploy is sent from the signer account, dry-run, Safe and unsigned modes don't apply")
+	}
+	if err := artifact.CheckABI(); err != nil {
+		return nil, err
+	}
+	auth, err := n.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the creation costs more than the default limit of calls, estimate it
+	auth.GasLimit = 0
+	address, tx, _, err := bind.DeployContract(auth, artifact.ABI, artifact.Bytecode, n.backend)
+	if err != nil {
+		n.nonces.Release(auth.Nonce.Uint64())
+		n.Sugar.Errorf("deploy error: %s", err)
+		return nil, err
+	}
+	n.Sugar.Infof("deploy sent, tx %s, contract %s", tx.Hash().String(), address.Hex())
+	receipt, err := n.waitNotified(ctx, "deploy", tx)
+	if err != nil {
+		return nil, err
+	}
+	if err = n.bind(receipt.ContractAddress); err != nil {
+		return nil, err
+	}
+	chainId, err := n.chainID(ctx)
+	if err != nil {
+		n.Sugar.Errorf("Get chainId error: %s", err)
+		return nil, err
+	}
+	d := &Deployment{
+		Address: receipt.ContractAddress,
+		Tx:      receipt.TxHash,
+		Block:   receipt.BlockNumber.Uint64(),
+		ChainID: chainId.Uint64(),
+	}
+
+	if err = n.transact(ctx, "initialize"); err != nil {
+		return d, fmt.Errorf("deployed at %s but initialize failed: %w", d.Address.Hex(), err)
+	}
+	if err = n.verifyDeployment(ctx, d, auth.From); err != nil {
+		return d, err
+	}
+	n.Sugar.Infof("contract %s deployed and initialized, %s (%s) owned by %s", d.Address.Hex(), d.Name, d.Symbol, d.Owner.Hex())
+	return d, nil
+}
+
+// verifyDeployment reads back the owner, name and symbol after initialize.
+func (n *Node) verifyDeployment(ctx context.Context, d *Deployment, deployer common.Address) error {
+	opts := &bind.CallOpts{Context: ctx}
+	var err error
+	if d.Owner, err = n.nft.Owner(opts); err != nil {
+		n.Sugar.Errorf("read owner error: %s", err)
+		return err
+	}
+	if d.Name, err = n.nft.Name(opts); err != nil {
+		n.Sugar.Errorf("read name error: %s", err)
+		return err
+	}
+	if d.Symbol, err = n.nft.Symbol(opts); err != nil {
+		n.Sugar.Errorf("read symbol error: %s", err)
+		return err
+	}
+	if d.Version, err = n.nft.Version(opts); err != nil {
+		n.Sugar.Errorf("read version error: %s", err)
+		return err
+	}
+	if d.Owner != deployer {
+		return fmt.Errorf("%w: contract %s is owned by %s after initialize, not the deployer %s", ErrNotOwner, d.Address.Hex(), d.Owner.Hex(), deployer.Hex())
+	}
+	if d.Name == "" || d.Symbol == "" {
+		return fmt.Errorf("contract %s has name %q and symbol %q after initialize", d.Address.Hex(), d.Name, d.Symbol)
+	}
+	return nil
+}
+
+// DeployedAt tells whether code is deployed at the contract address, at block,
+// the latest block if nil.
+func (n *Node) DeployedAt(ctx context.Context, block *big.Int) (bool, error) {
+	code, err := n.backend.CodeAt(ctx, n.contract, block)
+	if err != nil {
+		n.Sugar.Errorf("get code of %s error: %s", n.contract.Hex(), err)
+		return false, err
+	}
+	return len(code) > 0, nil
+}
@@ -0,0 +1,149 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+)
+
+// dryRunSamples is how many addresses of a list call get their quota change reported.
+const dryRunSamples = 5
+
+// StateDiff is a projected change of contract state.
+type StateDiff struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// DryRunReport is the outcome of simulating an admin call.
+type DryRunReport struct {
+	Method   string
+	From     common.Address
+	Block    *big.Int
+	OK       bool
+	Revert   string
+	Gas      uint64
+	GasPrice *big.Int
+	Fee      *big.Int
+	Diffs    []StateDiff
+}
+
+// SetDryRun makes the admin methods simulate their calls with eth_call from the
+// contract owner, instead of sending transactions. See DryRunReports.
+func (n *Node) SetDryRun(dryRun bool) {
+	n.dryRun = dryRun
+}
+
+// DryRunReports returns the reports of the calls simulated so far.
+func (n *Node) DryRunReports() []DryRunReport {
+	return n.dryRunReports
+}
+
+func (n *Node) simulate(ctx context.Context, method string, params ...interface{}) error {
+	data, err := carABI.Pack(method, params...)
+	if err != nil {
+		n.Sugar.Errorf("%s error: %s", method, err)
+		return err
+	}
+	head, err := n.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		n.Sugar.Errorf("get latest block error: %s", err)
+		return err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: head.Number}
+	owner, err := n.nft.Owner(opts)
+	if err != nil {
+		n.Sugar.Errorf("get owner error: %s", err)
+		return err
+	}
+	report := DryRunReport{Method: method, From: owner, Block: head.Number}
+	msg := ethereum.CallMsg{From: owner, To: &n.contract, Data: data}
+	if _, err = n.backend.CallContract(ctx, msg, head.Number); err != nil {
+		report.Revert = revertReason(err)
+		n.dryRunReports = append(n.dryRunReports, report)
+		n.Sugar.Infof("dry run %s reverted: %s", method, report.Revert)
+		return nil
+	}
+	report.OK = true
+	if report.Gas, err = n.backend.EstimateGas(ctx, msg); err != nil {
+		n.Sugar.Errorf("EstimateGas error: %s", err)
+		return err
+	}
+	if report.GasPrice, err = n.backend.SuggestGasPrice(ctx); err != nil {
+		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
+		return err
+	}
+	report.Fee = new(big.Int).Mul(report.GasPrice, new(big.Int).SetUint64(report.Gas))
+	if report.Diffs, err = n.projectDiffs(opts, method, params...); err != nil {
+		n.Sugar.Errorf("project state diff error: %s", err)
+		return err
+	}
+	n.dryRunReports = append(n.dryRunReports, report)
+	n.Sugar.Infof("dry run %s ok, gas %d", method, report.Gas)
+	return nil
+}
+
+// projectDiffs reads the state the call changes, and what it will be after.
+func (n *Node) projectDiffs(opts *bind.CallOpts, method string, params ...interface{}) ([]StateDiff, error) {
+	switch method {
+	case "setPhase":
+		phase, err := n.nft.Phase(opts)
+		if err != nil {
+			return nil, err
+		}
+		return []StateDiff{{"phase", fmt.Sprint(phase), fmt.Sprint(params[0])}}, nil
+	case "pause", "unpause":
+		paused, err := n.nft.Paused(opts)
+		if err != nil {
+			return nil, err
+		}
+		return []StateDiff{{"paused", fmt.Sprint(paused), fmt.Sprint(method == "pause")}}, nil
+	case "addWhitelist", "addAirdrop":
+		owners, amount := params[0].([]common.Address), params[1].(uint8)
+		quota := n.nft.MintQuota
+		if method == "addAirdrop" {
+			quota = n.nft.AirdropQuota
+		}
+		var diffs []StateDiff
+		for i, owner := range owners {
+			if i == dryRunSamples {
+				diffs = append(diffs, StateDiff{Field: fmt.Sprintf("... %d more addresses", len(owners)-i)})
+				break
+			}
+			q, err := quota(opts, owner)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, StateDiff{
+				Field:  fmt.Sprintf("%s(%s)", method, owner.Hex()),
+				Before: fmt.Sprintf("minted %d, cap %d", q.Minted, q.Cap),
+				After:  fmt.Sprintf("minted %d, cap %d", q.Minted, amount),
+			})
+		}
+		return diffs, nil
+	}
+	return nil, nil
+}
+
+// revertReason decodes the revert reason carried by a call error.
+func revertReason(err error) string {
+	var de rpc.DataError
+	if errors.As(err, &de) {
+		if s, ok := de.ErrorData().(string); ok {
+			if data, e := hexutil.Decode(s); e == nil {
+				if reason, e := abi.UnpackRevert(data); e == nil {
+					return reason
+				}
+			}
+		}
+	}
+	return err.Error()
+}
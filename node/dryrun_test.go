@@ -0,0 +1,158 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+// revertError is a node's error for a reverted call, the revert data in its
+// error data as geth sends it.
+type revertError string
+
+func (e revertError) Error() string {
+	return "execution reverted: " + string(e)
+}
+
+func (e revertError) ErrorData() interface{} {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, _ := abi.Arguments{{Type: stringType}}.Pack(string(e))
+	return hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], data...))
+}
+
+// contractState answers the contract's getters from its fields, and reverts
+// its transactions with revert when set.
+type contractState struct {
+	Backend
+	contract  common.Address
+	owner     common.Address
+	phase     int8
+	paused    bool
+	whitelist map[common.Address][2]uint8
+	revert    string
+}
+
+func (c *contractState) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if call.To == nil || *call.To != c.contract {
+		return c.Backend.CallContract(ctx, call, blockNumber)
+	}
+	method, err := carABI.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "owner":
+		return method.Outputs.Pack(c.owner)
+	case "phase":
+		return method.Outputs.Pack(c.phase)
+	case "paused":
+		return method.Outputs.Pack(c.paused)
+	case "mintQuota", "airdropQuota":
+		q := c.whitelist[args[0].(common.Address)]
+		return method.Outputs.Pack(q[0], q[1])
+	}
+	if c.revert != "" {
+		return nil, revertError(c.revert)
+	}
+	return nil, nil
+}
+
+// newStateNode returns a Node on a contractState, owned by alice in phase 1.
+func newStateNode(t *testing.T) (*Node, *contractState) {
+	t.Helper()
+	sim, cfg := newBare(t)
+	c := &contractState{
+		Backend:   sim,
+		contract:  common.HexToAddress(cfg.Contract),
+		owner:     common.HexToAddress("0x00000000000000000000000000000000000a11ce"),
+		phase:     1,
+		whitelist: make(map[common.Address][2]uint8),
+	}
+	n := NewWithBackend(cfg, c)
+	if err := n.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return n, c
+}
+
+func newDryRun(t *testing.T) (*Node, *contractState) {
+	t.Helper()
+	n, c := newStateNode(t)
+	n.SetDryRun(true)
+	return n, c
+}
+
+func TestDryRunDiffs(t *testing.T) {
+	n, c := newDryRun(t)
+	ctx := context.Background()
+	if err := n.SetPhase(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	owners := addresses(dryRunSamples + 2)
+	c.whitelist[owners[0]] = [2]uint8{1, 2}
+	if err := n.transact(ctx, "addWhitelist", owners, uint8(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := n.DryRunReports()
+	if len(reports) != 3 {
+		t.Fatalf("%d reports, want 3", len(reports))
+	}
+	for _, r := range reports {
+		if !r.OK || r.From != c.owner || r.Gas == 0 || r.Fee.Cmp(new(big.Int).Mul(r.GasPrice, new(big.Int).SetUint64(r.Gas))) != 0 {
+			t.Errorf("%s: report %+v, want ok from the owner with its fee", r.Method, r)
+		}
+	}
+	if d := reports[0].Diffs; len(d) != 1 || d[0] != (StateDiff{"phase", "1", "2"}) {
+		t.Errorf("setPhase diffs %v", d)
+	}
+	if d := reports[1].Diffs; len(d) != 1 || d[0] != (StateDiff{"paused", "false", "true"}) {
+		t.Errorf("pause diffs %v", d)
+	}
+	d := reports[2].Diffs
+	if len(d) != dryRunSamples+1 {
+		t.Fatalf("addWhitelist diffs %v, want %d samples and the rest counted", d, dryRunSamples)
+	}
+	if want := (StateDiff{"addWhitelist(" + owners[0].Hex() + ")", "minted 1, cap 2", "minted 1, cap 3"}); d[0] != want {
+		t.Errorf("diff %+v, want %+v", d[0], want)
+	}
+	if want := "... 2 more addresses"; d[dryRunSamples].Field != want {
+		t.Errorf("last diff %q, want %q", d[dryRunSamples].Field, want)
+	}
+}
+
+func TestDryRunRevert(t *testing.T) {
+	n, c := newDryRun(t)
+	c.revert = "Pausable: paused"
+	if err := n.Pause(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := n.DryRunReports()[0]
+	if r.OK || r.Revert != "Pausable: paused" || r.Diffs != nil {
+		t.Errorf("report %+v, want reverted with Pausable: paused", r)
+	}
+}
+
+func TestRevertReason(t *testing.T) {
+	for err, want := range map[error]string{
+		revertError("Ownable: caller is not the owner"): "Ownable: caller is not the owner",
+		errors.New("execution reverted"):                "execution reverted",
+	} {
+		if got := revertReason(err); got != want {
+			t.Errorf("revertReason(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"strings"
+)
+
+// ENSRegistry is the ENS registry on mainnet and the public testnets.
+var ENSRegistry = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// ErrENSNotFound is returned for a name without a resolver or an address.
+var ErrENSNotFound = errors.New("ENS name not found")
+
+const ensABI = `[
+{"name":"resolver","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"addr","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"name","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"string"}]}
+]`
+
+var ensParsedABI, _ = abi.JSON(strings.NewReader(ensABI))
+
+// IsENSName tells whether s is meant as an ENS name rather than an address.
+func IsENSName(s string) bool {
+	return strings.HasSuffix(strings.ToLower(s), ".eth")
+}
+
+// NameHash is the ENS namehash of name. Names are only lowercased, not fully
+// UTS-46 normalized, which covers the plain ascii names used in practice.
+func NameHash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node.Bytes(), crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// ENS resolves names through an ENS registry.
+type ENS struct {
+	registry common.Address
+	caller   bind.ContractCaller
+}
+
+func NewENS(registry common.Address, caller bind.ContractCaller) *ENS {
+	return &ENS{registry: registry, caller: caller}
+}
+
+func (e *ENS) call(opts *bind.CallOpts, contract common.Address, method string, node common.Hash) (interface{}, error) {
+	var out []interface{}
+	c := bind.NewBoundContract(contract, ensParsedABI, e.caller, nil, nil)
+	if err := c.Call(opts, &out, method, node); err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// resolver returns the resolver of node, zero if it has none.
+func (e *ENS) resolver(opts *bind.CallOpts, node common.Hash) (common.Address, error) {
+	out, err := e.call(opts, e.registry, "resolver", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return out.(common.Address), nil
+}
+
+// Resolve returns the address name points to at block, the latest block if nil.
+func (e *ENS) Resolve(ctx context.Context, name string, block *big.Int) (common.Address, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	node := NameHash(name)
+	resolver, err := e.resolver(opts, node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if resolver == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s has no resolver", ErrENSNotFound, name)
+	}
+	out, err := e.call(opts, resolver, "addr", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address := out.(common.Address)
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s has no address", ErrENSNotFound, name)
+	}
+	return address, nil
+}
+
+// Lookup returns the primary name of address at block, empty if it has none.
+// The name is only returned if it resolves back to address, as anyone can
+// claim any name in the reverse record.
+func (e *ENS) Lookup(ctx context.Context, address common.Address, block *big.Int) (string, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	node := NameHash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+	resolver, err := e.resolver(opts, node)
+	if err != nil || resolver == (common.Address{}) {
+		return "", err
+	}
+	out, err := e.call(opts, resolver, "name", node)
+	if err != nil {
+		return "", err
+	}
+	name := out.(string)
+	if name == "" {
+		return "", nil
+	}
+	forward, err := e.Resolve(ctx, name, block)
+	if errors.Is(err, ErrENSNotFound) {
+		return "", nil
+	}
+	if err != nil || forward != address {
+		return "", err
+	}
+	return name, nil
+}
+
+// ResolveName resolves an ENS name at block, the latest block if nil.
+func (n *Node) ResolveName(ctx context.Context, name string, block *big.Int) (common.Address, error) {
+	address, err := n.ens.Resolve(ctx, name, block)
+	if err != nil {
+		n.Sugar.Errorf("resolve %s error: %s", name, err)
+		return common.Address{}, err
+	}
+	n.Sugar.Infof("resolved %s to %s at block %v", name, address.Hex(), block)
+	return address, nil
+}
+
+// LookupAddress returns the verified primary ENS name of address at block, empty
+// if it has none.
+func (n *Node) LookupAddress(ctx context.Context, address common.Address, block *big.Int) (string, error) {
+	name, err := n.ens.Lookup(ctx, address, block)
+	if err != nil {
+		n.Sugar.Errorf("lookup %s error: %s", address.Hex(), err)
+	}
+	return name, err
+}
+
+// LookupHoldings sets the primary ENS name of every holder, looked up at the
+// block of its holding.
+func (n *Node) LookupHoldings(ctx context.Context, holdings []Holding) error {
+	for i := range holdings {
+		name, err := n.LookupAddress(ctx, holdings[i].Owner, holdings[i].Block)
+		if err != nil {
+			return err
+		}
+		holdings[i].ENS = name
+	}
+	return nil
+}
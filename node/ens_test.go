@@ -0,0 +1,124 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/cybercar-nft/go-cybercar/node/enstest"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestNameHash(t *testing.T) {
+	// from EIP-137
+	for name, want := range map[string]string{
+		"":          "0x0000000000000000000000000000000000000000000000000000000000000000",
+		"eth":       "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae",
+		"foo.eth":   "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+		"Foo.ETH":   "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f",
+		"alice.eth": "0x787192fc5378cc32aa956ddfdedbf26b24e8d78e40109add0eea2c1a012c3dec",
+	} {
+		if got := NameHash(name).Hex(); got != want {
+			t.Errorf("NameHash(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+// newTestENS deploys the mock registry on a simulated chain, read through
+// enstest.Archive so calls pinned to past blocks see their state.
+func newTestENS(t *testing.T) (*ENS, *enstest.ENS, *backends.SimulatedBackend) {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(testChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc := core.GenesisAlloc{auth.From: {Balance: new(big.Int).Lsh(big.NewInt(1), 100)}}
+	backend := backends.NewSimulatedBackend(alloc, 10_000_000)
+	t.Cleanup(func() { _ = backend.Close() })
+	mock, err := enstest.Deploy(auth, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewENS(mock.Registry, enstest.Archive{SimulatedBackend: backend}), mock, backend
+}
+
+func reverseNode(address common.Address) common.Hash {
+	return NameHash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+}
+
+func TestENSResolve(t *testing.T) {
+	ens, mock, backend := newTestENS(t)
+	ctx := context.Background()
+	alice := common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+
+	if _, err := ens.Resolve(ctx, "alice.eth", nil); !errors.Is(err, ErrENSNotFound) {
+		t.Fatalf("unregistered name: err = %v, want ErrENSNotFound", err)
+	}
+	if err := mock.SetAddr(NameHash("alice.eth"), alice); err != nil {
+		t.Fatal(err)
+	}
+	pinned := backend.Blockchain().CurrentBlock().Number()
+	got, err := ens.Resolve(ctx, "Alice.eth", nil)
+	if err != nil || got != alice {
+		t.Fatalf("Resolve = %s, %v, want %s", got.Hex(), err, alice.Hex())
+	}
+
+	if got, err = ens.Resolve(ctx, "alice.eth", pinned); err != nil || got != alice {
+		t.Fatalf("at block %s = %s, %v, want %s", pinned, got.Hex(), err, alice.Hex())
+	}
+
+	// the name moves on, the pinned block still reads the old address
+	if err := mock.SetAddr(NameHash("alice.eth"), bob); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = ens.Resolve(ctx, "alice.eth", nil); got != bob {
+		t.Fatalf("latest = %s, want %s", got.Hex(), bob.Hex())
+	}
+	if got, err = ens.Resolve(ctx, "alice.eth", pinned); err != nil || got != alice {
+		t.Fatalf("at block %s = %s, %v, want %s", pinned, got.Hex(), err, alice.Hex())
+	}
+
+	if err = mock.ClearResolver(NameHash("alice.eth")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ens.Resolve(ctx, "alice.eth", nil); !errors.Is(err, ErrENSNotFound) {
+		t.Fatalf("no resolver: err = %v, want ErrENSNotFound", err)
+	}
+	if _, err = NewENS(common.HexToAddress("0x1234"), backend).Resolve(ctx, "alice.eth", nil); err == nil {
+		t.Fatal("no registry: want an error")
+	}
+}
+
+func TestENSLookup(t *testing.T) {
+	ens, mock, _ := newTestENS(t)
+	ctx := context.Background()
+	alice := common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	mallory := common.HexToAddress("0x000000000000000000000000000000000ba11e7")
+
+	if name, err := ens.Lookup(ctx, alice, nil); err != nil || name != "" {
+		t.Fatalf("no reverse record: Lookup = %q, %v", name, err)
+	}
+	if err := mock.SetAddr(NameHash("alice.eth"), alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.SetName(reverseNode(alice), "alice.eth"); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := ens.Lookup(ctx, alice, nil); err != nil || name != "alice.eth" {
+		t.Fatalf("Lookup = %q, %v, want alice.eth", name, err)
+	}
+	// claiming a name which doesn't resolve back gives nothing
+	if err := mock.SetName(reverseNode(mallory), "alice.eth"); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := ens.Lookup(ctx, mallory, nil); err != nil || name != "" {
+		t.Fatalf("unverified Lookup = %q, %v, want none", name, err)
+	}
+}
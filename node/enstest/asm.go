@@ -0,0 +1,109 @@
+package enstest
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"sort"
+)
+
+// program is the bytecode of a mock contract under construction. Jump
+// targets are labels, resolved by bytes.
+type program struct {
+	code   []byte
+	labels map[string]int
+	fixups map[int]string // offset of a PUSH2 operand to the label it pushes
+}
+
+func newProgram() *program {
+	return &program{labels: make(map[string]int), fixups: make(map[int]string)}
+}
+
+func (p *program) op(ops ...vm.OpCode) *program {
+	for _, op := range ops {
+		p.code = append(p.code, byte(op))
+	}
+	return p
+}
+
+// push appends the PUSH of b, at most 32 bytes.
+func (p *program) push(b ...byte) *program {
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	p.code = append(p.code, byte(vm.PUSH1)+byte(len(b)-1))
+	p.code = append(p.code, b...)
+	return p
+}
+
+func (p *program) label(name string) *program {
+	p.labels[name] = len(p.code)
+	return p.op(vm.JUMPDEST)
+}
+
+// jumpi jumps to label name if the top of the stack is not zero.
+func (p *program) jumpi(name string) *program {
+	p.code = append(p.code, byte(vm.PUSH2))
+	p.fixups[len(p.code)] = name
+	p.code = append(p.code, 0, 0)
+	return p.op(vm.JUMPI)
+}
+
+// dispatch jumps to the label of the called function, keyed by signature, and
+// reverts if none matches.
+func (p *program) dispatch(functions map[string]string) *program {
+	signatures := make([]string, 0, len(functions))
+	for signature := range functions {
+		signatures = append(signatures, signature)
+	}
+	sort.Strings(signatures)
+	for _, signature := range signatures {
+		p.push(0).op(vm.CALLDATALOAD).push(224).op(vm.SHR).
+			push(crypto.Keccak256([]byte(signature))[:4]...).op(vm.EQ).jumpi(functions[signature])
+	}
+	return p.push(0).op(vm.DUP1, vm.REVERT)
+}
+
+// arg pushes the i-th word of the call arguments.
+func (p *program) arg(i int) *program {
+	return p.push(byte(4 + 32*i)).op(vm.CALLDATALOAD)
+}
+
+// mapSlot turns the key on the stack into its storage slot in the mapping
+// at slot base, as solidity lays it out.
+func (p *program) mapSlot(base byte) *program {
+	return p.push(0).op(vm.MSTORE).push(base).push(32).op(vm.MSTORE).push(64).push(0).op(vm.SHA3)
+}
+
+// returnWord returns the top of the stack.
+func (p *program) returnWord() *program {
+	return p.push(0).op(vm.MSTORE).push(32).push(0).op(vm.RETURN)
+}
+
+func (p *program) bytes() ([]byte, error) {
+	code := append([]byte{}, p.code...)
+	for offset, name := range p.fixups {
+		target, ok := p.labels[name]
+		if !ok {
+			return nil, fmt.Errorf("label %s undefined", name)
+		}
+		code[offset], code[offset+1] = byte(target>>8), byte(target)
+	}
+	return code, nil
+}
+
+// deployCode wraps runtime code into init code which returns it.
+func deployCode(runtime []byte) []byte {
+	// PUSH2 len DUP1 PUSH1 12 PUSH1 0 CODECOPY PUSH1 0 RETURN, 12 bytes
+	n := len(runtime)
+	init := []byte{
+		byte(vm.PUSH2), byte(n >> 8), byte(n),
+		byte(vm.DUP1),
+		byte(vm.PUSH1), 12,
+		byte(vm.PUSH1), 0,
+		byte(vm.CODECOPY),
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}
+	return append(init, runtime...)
+}
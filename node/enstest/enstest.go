@@ -0,0 +1,163 @@
+// Package enstest deploys a mock ENS registry and resolver on a simulated
+// backend, good enough to exercise node.ENS through real contract calls.
+//
+// The registry keeps resolver(node) and the resolver keeps addr(node) and
+// name(node), names of at most 32 bytes. Anyone may set anything, so it is
+// nothing like the real ENS beyond the read interface.
+package enstest
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"math/big"
+	"strings"
+)
+
+const mockABI = `[
+{"name":"setResolver","type":"function","stateMutability":"nonpayable","inputs":[{"name":"node","type":"bytes32"},{"name":"resolver","type":"address"}],"outputs":[]},
+{"name":"setAddr","type":"function","stateMutability":"nonpayable","inputs":[{"name":"node","type":"bytes32"},{"name":"addr","type":"address"}],"outputs":[]},
+{"name":"setName","type":"function","stateMutability":"nonpayable","inputs":[{"name":"node","type":"bytes32"},{"name":"name","type":"string"}],"outputs":[]}
+]`
+
+// ENS is a deployed mock registry with one resolver.
+type ENS struct {
+	Registry common.Address
+	Resolver common.Address
+
+	auth     *bind.TransactOpts
+	backend  *backends.SimulatedBackend
+	registry *bind.BoundContract
+	resolver *bind.BoundContract
+}
+
+// registryCode is the runtime code of the registry, resolver(node) in the
+// mapping at slot 0.
+func registryCode() ([]byte, error) {
+	p := newProgram().dispatch(map[string]string{
+		"resolver(bytes32)":            "resolver",
+		"setResolver(bytes32,address)": "setResolver",
+	})
+	p.label("resolver").arg(0).mapSlot(0).op(vm.SLOAD).returnWord()
+	p.label("setResolver").arg(1).arg(0).mapSlot(0).op(vm.SSTORE, vm.STOP)
+	return p.bytes()
+}
+
+// resolverCode is the runtime code of the resolver, addr(node) in the mapping
+// at slot 0, the name in mapping 1 and its length in mapping 2.
+func resolverCode() ([]byte, error) {
+	p := newProgram().dispatch(map[string]string{
+		"addr(bytes32)":            "addr",
+		"setAddr(bytes32,address)": "setAddr",
+		"name(bytes32)":            "name",
+		"setName(bytes32,string)":  "setName",
+	})
+	p.label("addr").arg(0).mapSlot(0).op(vm.SLOAD).returnWord()
+	p.label("setAddr").arg(1).arg(0).mapSlot(0).op(vm.SSTORE, vm.STOP)
+	// both slots are hashed in memory 0..64 before the string is written there
+	p.label("name").
+		arg(0).mapSlot(1).op(vm.SLOAD).
+		arg(0).mapSlot(2).op(vm.SLOAD).
+		push(32).op(vm.MSTORE).push(64).op(vm.MSTORE).
+		push(32).push(0).op(vm.MSTORE).
+		push(96).push(0).op(vm.RETURN)
+	// the string is at 4 + its offset: the length, then the first word
+	p.label("setName").
+		arg(1).push(4).op(vm.ADD).
+		op(vm.DUP1, vm.CALLDATALOAD).arg(0).mapSlot(2).op(vm.SSTORE).
+		push(32).op(vm.ADD, vm.CALLDATALOAD).arg(0).mapSlot(1).op(vm.SSTORE, vm.STOP)
+	return p.bytes()
+}
+
+// Deploy deploys the registry and the resolver from auth, and commits.
+func Deploy(auth *bind.TransactOpts, backend *backends.SimulatedBackend) (*ENS, error) {
+	parsed, err := abi.JSON(strings.NewReader(mockABI))
+	if err != nil {
+		return nil, err
+	}
+	e := &ENS{auth: auth, backend: backend}
+	for _, c := range []struct {
+		code     func() ([]byte, error)
+		address  *common.Address
+		contract **bind.BoundContract
+	}{
+		{registryCode, &e.Registry, &e.registry},
+		{resolverCode, &e.Resolver, &e.resolver},
+	} {
+		runtime, err := c.code()
+		if err != nil {
+			return nil, err
+		}
+		*c.address, _, *c.contract, err = bind.DeployContract(auth, parsed, deployCode(runtime), backend)
+		if err != nil {
+			return nil, err
+		}
+	}
+	backend.Commit()
+	return e, nil
+}
+
+func (e *ENS) transact(c *bind.BoundContract, method string, params ...interface{}) error {
+	if _, err := c.Transact(e.auth, method, params...); err != nil {
+		return err
+	}
+	e.backend.Commit()
+	return nil
+}
+
+// SetAddr points node to address, through the resolver.
+func (e *ENS) SetAddr(node common.Hash, address common.Address) error {
+	if err := e.transact(e.registry, "setResolver", node, e.Resolver); err != nil {
+		return err
+	}
+	return e.transact(e.resolver, "setAddr", node, address)
+}
+
+// SetName sets the name of the reverse node, through the resolver.
+func (e *ENS) SetName(node common.Hash, name string) error {
+	if len(name) > 32 {
+		return fmt.Errorf("name %q longer than 32 bytes", name)
+	}
+	if err := e.transact(e.registry, "setResolver", node, e.Resolver); err != nil {
+		return err
+	}
+	return e.transact(e.resolver, "setName", node, name)
+}
+
+// ClearResolver removes the resolver of node.
+func (e *ENS) ClearResolver(node common.Hash) error {
+	return e.transact(e.registry, "setResolver", node, common.Address{})
+}
+
+// Archive is the simulated backend also answering calls at past blocks, which
+// it refuses, from the state of those blocks.
+type Archive struct {
+	*backends.SimulatedBackend
+}
+
+func (a Archive) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	chain := a.Blockchain()
+	if blockNumber == nil || blockNumber.Cmp(chain.CurrentBlock().Number()) == 0 {
+		return a.SimulatedBackend.CallContract(ctx, call, blockNumber)
+	}
+	if !blockNumber.IsUint64() {
+		return nil, fmt.Errorf("block %s not found", blockNumber)
+	}
+	block := chain.GetBlockByNumber(blockNumber.Uint64())
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", blockNumber)
+	}
+	state, err := chain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	evm := vm.NewEVM(core.NewEVMBlockContext(block.Header(), chain, nil), vm.TxContext{Origin: call.From, GasPrice: new(big.Int)}, state, chain.Config(), vm.Config{NoBaseFee: true})
+	ret, _, err := evm.StaticCall(vm.AccountRef(call.From), *call.To, call.Data, 50_000_000)
+	return ret, err
+}
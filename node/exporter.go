@@ -0,0 +1,367 @@
+package node
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// observedBackend reports the latency and errors of the reads of a Backend.
+type observedBackend struct {
+	Backend
+	observe func(method string, d time.Duration, err error)
+}
+
+func (b *observedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	start := time.Now()
+	out, err := b.Backend.CallContract(ctx, call, blockNumber)
+	b.observe("eth_call", time.Since(start), err)
+	return out, err
+}
+
+func (b *observedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	start := time.Now()
+	header, err := b.Backend.HeaderByNumber(ctx, number)
+	b.observe("eth_getBlockByNumber", time.Since(start), err)
+	return header, err
+}
+
+func (b *observedBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	start := time.Now()
+	logs, err := b.Backend.FilterLogs(ctx, q)
+	b.observe("eth_getLogs", time.Since(start), err)
+	return logs, err
+}
+
+func (b *observedBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	start := time.Now()
+	balance, err := b.Backend.BalanceAt(ctx, account, blockNumber)
+	b.observe("eth_getBalance", time.Since(start), err)
+	return balance, err
+}
+
+// observedChainBackend is an observedBackend which tells its chain id, as
+// the backend it wraps does.
+type observedChainBackend struct {
+	*observedBackend
+}
+
+func (b observedChainBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	start := time.Now()
+	id, err := b.Backend.(chainIDReader).ChainID(ctx)
+	b.observe("eth_chainId", time.Since(start), err)
+	return id, err
+}
+
+// observeRPC passes the latency and errors of the rpc reads to observe from
+// now on.
+func (n *Node) observeRPC(observe func(method string, d time.Duration, err error)) error {
+	observed := &observedBackend{Backend: n.backend, observe: observe}
+	n.backend = observed
+	if _, ok := observed.Backend.(chainIDReader); ok {
+		n.backend = observedChainBackend{observed}
+	}
+	return n.bind(n.contract)
+}
+
+// Exporter serves the state of the contract as Prometheus metrics, read with
+// the contract calls every interval and kept up to date with its events.
+type Exporter struct {
+	n        *Node
+	registry *prometheus.Registry
+
+	totalSupply       prometheus.Gauge
+	reserved          prometheus.Gauge
+	capacity          prometheus.Gauge
+	capacityRemaining prometheus.Gauge
+	phase             prometheus.Gauge
+	paused            prometheus.Gauge
+	contractBalance   prometheus.Gauge
+	ownerBalance      prometheus.Gauge
+	holders           prometheus.Gauge
+	block             prometheus.Gauge
+	lastRefresh       prometheus.Gauge
+	transfers         prometheus.Counter
+	transfersPerBlock prometheus.Histogram
+	events            *prometheus.CounterVec
+	rpcDuration       *prometheus.HistogramVec
+	rpcErrors         *prometheus.CounterVec
+
+	mu sync.Mutex
+	// owners is the owner of every token id, balances the tokens of every
+	// owner, for the unique holders.
+	owners   map[string]common.Address
+	balances map[common.Address]int
+}
+
+// ExporterOptions controls Exporter.Run.
+type ExporterOptions struct {
+	// Interval between the reads of the contract state, and the polls of the
+	// events, default 15 seconds.
+	Interval time.Duration
+	// Confirmations is how far behind the latest block the events are read.
+	Confirmations uint64
+}
+
+// NewExporter returns the exporter of the contract, every rpc read of the
+// node is measured from now on.
+func (n *Node) NewExporter() (*Exporter, error) {
+	gauge := func(name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{Namespace: "cybercar", Name: name, Help: help})
+	}
+	e := &Exporter{
+		n:                 n,
+		registry:          prometheus.NewRegistry(),
+		totalSupply:       gauge("total_supply", "Tokens minted."),
+		reserved:          gauge("reserved", "Reserve quota left, tokens set aside by addReserve and not minted yet."),
+		capacity:          gauge("capacity", "Tokens which can ever be minted."),
+		capacityRemaining: gauge("capacity_remaining", "Tokens which can still be minted, 0 when sold out."),
+		phase:             gauge("phase", "Sale phase, 0 closed, 1 whitelist, 2 public."),
+		paused:            gauge("paused", "1 if the contract is paused."),
+		contractBalance:   gauge("contract_balance_ether", "Ether held by the contract."),
+		ownerBalance:      gauge("owner_balance_ether", "Ether of the contract owner account."),
+		holders:           gauge("holders", "Accounts holding at least one token."),
+		block:             gauge("block", "Last block the events were read to."),
+		lastRefresh:       gauge("last_refresh_timestamp_seconds", "Unix time the contract state was last read."),
+		transfers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "cybercar", Name: "transfers_total", Help: "Transfer events, mints included.",
+		}),
+		transfersPerBlock: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "cybercar", Name: "transfers_per_block", Help: "Transfer events in each block.",
+			Buckets: []float64{0, 1, 2, 5, 10, 20, 50, 100, 200},
+		}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cybercar", Name: "events_total", Help: "Events of the contract.",
+		}, []string{"event"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "cybercar", Name: "rpc_duration_seconds", Help: "Latency of the rpc requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cybercar", Name: "rpc_errors_total", Help: "Failed rpc requests.",
+		}, []string{"method"}),
+		owners:   make(map[string]common.Address),
+		balances: make(map[common.Address]int),
+	}
+	for _, c := range []prometheus.Collector{
+		e.totalSupply, e.reserved, e.capacity, e.capacityRemaining, e.phase, e.paused,
+		e.contractBalance, e.ownerBalance, e.holders, e.block, e.lastRefresh,
+		e.transfers, e.transfersPerBlock, e.events, e.rpcDuration, e.rpcErrors,
+	} {
+		if err := e.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := n.observeRPC(e.observeRPC); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Exporter) observeRPC(method string, d time.Duration, err error) {
+	e.rpcDuration.WithLabelValues(method).Observe(d.Seconds())
+	if err != nil {
+		e.rpcErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// Handler serves the metrics.
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// Run reads the holders and the state of the contract, then keeps them up to
+// date until ctx is done: the state every interval, the holders and the
+// transfers from the events.
+func (e *Exporter) Run(ctx context.Context, opts ExporterOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	block, err := e.n.PinBlock(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = e.seedHolders(ctx, block); err != nil {
+		return err
+	}
+	e.refresh(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				e.refresh(ctx)
+			}
+		}
+	}()
+	return e.n.Watch(ctx, WatchOptions{
+		From:          block.Uint64() + 1,
+		Confirmations: opts.Confirmations,
+		Interval:      interval,
+		Handle:        e.handle,
+	})
+}
+
+// seedHolders reads the owner of every token at block: from the Transfer logs
+// since Config.DeployBlock if it is set, otherwise enumerating the tokens with
+// tokenByIndex, whatever their ids.
+func (e *Exporter) seedHolders(ctx context.Context, block *big.Int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	if from := e.n.cfg.DeployBlock; from > 0 {
+		err = e.seedFromLogs(ctx, from, block.Uint64())
+	} else {
+		err = e.seedByIndex(ctx, block)
+	}
+	if err != nil {
+		return err
+	}
+	e.holders.Set(float64(len(e.balances)))
+	e.block.Set(float64(block.Uint64()))
+	e.n.Sugar.Infof("%d tokens held by %d accounts at block %s", len(e.owners), len(e.balances), block)
+	return nil
+}
+
+// seedFromLogs replays the transfers of blocks from to to.
+func (e *Exporter) seedFromLogs(ctx context.Context, from, to uint64) error {
+	for start := from; start <= to; start += defaultMaxRange {
+		end := start + defaultMaxRange - 1
+		if end > to {
+			end = to
+		}
+		it, err := e.n.nft.FilterTransfer(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, nil, nil, nil)
+		if err != nil {
+			e.n.Sugar.Errorf("filter Transfer %d-%d error: %s", start, end, err)
+			return err
+		}
+		for it.Next() {
+			e.setOwner(it.Event.TokenId, it.Event.To)
+		}
+		err = it.Error()
+		_ = it.Close()
+		if err != nil {
+			e.n.Sugar.Errorf("read Transfer %d-%d error: %s", start, end, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// seedByIndex reads the owner of every token of tokenByIndex.
+func (e *Exporter) seedByIndex(ctx context.Context, block *big.Int) error {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	total, err := e.n.nft.TotalSupply(opts)
+	if err != nil {
+		e.n.Sugar.Errorf("get totalSupply error: %s", err)
+		return err
+	}
+	for i := big.NewInt(0); i.Cmp(total) < 0; i.Add(i, big.NewInt(1)) {
+		id, err := e.n.nft.TokenByIndex(opts, i)
+		if err != nil {
+			e.n.Sugar.Errorf("tokenByIndex %s error: %s", i, err)
+			return err
+		}
+		owner, err := e.n.nft.OwnerOf(opts, id)
+		if err != nil {
+			e.n.Sugar.Errorf("ownerOf %s error: %s", id, err)
+			return err
+		}
+		e.setOwner(id, owner)
+	}
+	return nil
+}
+
+// setOwner moves token id to owner, the zero address burns it.
+func (e *Exporter) setOwner(id *big.Int, owner common.Address) {
+	key := id.String()
+	if previous, ok := e.owners[key]; ok {
+		if e.balances[previous]--; e.balances[previous] <= 0 {
+			delete(e.balances, previous)
+		}
+		delete(e.owners, key)
+	}
+	if owner != (common.Address{}) {
+		e.owners[key] = owner
+		e.balances[owner]++
+	}
+}
+
+// refresh reads the state of the contract; a failed read is logged and
+// counted in the rpc errors, the gauges keep their last values.
+func (e *Exporter) refresh(ctx context.Context) {
+	info, err := e.n.Info(ctx, nil)
+	if err != nil {
+		e.n.Sugar.Warnf("read contract state error: %s", err)
+		return
+	}
+	e.totalSupply.Set(bigFloat(info.TotalSupply))
+	e.reserved.Set(bigFloat(info.Reserved))
+	e.capacity.Set(bigFloat(info.Capacity))
+	e.capacityRemaining.Set(bigFloat(new(big.Int).Sub(info.Capacity, info.TotalSupply)))
+	e.phase.Set(float64(info.Phase))
+	e.paused.Set(boolFloat(info.Paused))
+	e.contractBalance.Set(etherFloat(info.Balance))
+	balance, err := e.n.Balance(ctx, info.Owner)
+	if err != nil {
+		e.n.Sugar.Warnf("get owner balance error: %s", err)
+		return
+	}
+	e.ownerBalance.Set(etherFloat(balance))
+	e.lastRefresh.SetToCurrentTime()
+}
+
+// handle counts the events of blocks from to to, and follows the transfers
+// and pauses at once rather than at the next refresh.
+func (e *Exporter) handle(from, to uint64, events []ContractEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	perBlock := make(map[uint64]int)
+	for _, ev := range events {
+		e.events.WithLabelValues(ev.Name).Inc()
+		switch ev.Name {
+		case "Transfer":
+			perBlock[ev.Block]++
+			e.transfers.Inc()
+			e.setOwner(ev.TokenId, ev.To)
+		case "Paused":
+			e.paused.Set(1)
+		case "Unpaused":
+			e.paused.Set(0)
+		}
+	}
+	for block := from; block <= to; block++ {
+		e.transfersPerBlock.Observe(float64(perBlock[block]))
+	}
+	e.holders.Set(float64(len(e.balances)))
+	e.block.Set(float64(to))
+}
+
+func bigFloat(i *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
+
+func etherFloat(wei *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return f
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
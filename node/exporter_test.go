@@ -0,0 +1,251 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape returns the samples served by the exporter, by name with labels.
+func scrape(t *testing.T, e *Exporter) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("bad sample %q", line)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+func TestExporter(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	owner, _ := h.owner.Owner(ctx)
+	claimer, err := h.other.signerAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.owner.AddAirdrop(ctx, []common.Address{claimer.Address(), owner}, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.owner.SetPhase(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.other.transact(ctx, "claim", uint8(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := h.other.NewExporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := h.other.PinBlock(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.seedHolders(ctx, block); err != nil {
+		t.Fatal(err)
+	}
+	e.refresh(ctx)
+	m := scrape(t, e)
+	for name, want := range map[string]float64{
+		"cybercar_total_supply":       3,
+		"cybercar_capacity_remaining": m["cybercar_capacity"] - 3,
+		"cybercar_phase":              1,
+		"cybercar_paused":             0,
+		"cybercar_holders":            1,
+	} {
+		if m[name] != want {
+			t.Errorf("%s = %v, want %v", name, m[name], want)
+		}
+	}
+	if m["cybercar_capacity"] == 0 || m["cybercar_owner_balance_ether"] == 0 {
+		t.Errorf("capacity %v, owner balance %v, want them read", m["cybercar_capacity"], m["cybercar_owner_balance_ether"])
+	}
+	if m[`cybercar_rpc_duration_seconds_count{method="eth_call"}`] == 0 {
+		t.Error("eth_call latency not observed")
+	}
+
+	// the events after the seed block move the holders and the pause at once
+	if err = h.owner.transact(ctx, "claim", uint8(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.owner.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	next := block.Uint64() + 1
+	for behind := true; behind; {
+		if behind, err = h.other.watchRange(ctx, &next, 0, 2000, e.handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m = scrape(t, e)
+	for name, want := range map[string]float64{
+		"cybercar_holders":                                          2,
+		"cybercar_paused":                                           1,
+		"cybercar_transfers_total":                                  2,
+		`cybercar_events_total{event="Paused"}`:                     1,
+		`cybercar_transfers_per_block_bucket{le="1"}`:               1,
+		`cybercar_transfers_per_block_bucket{le="2"}`:               2,
+		`cybercar_transfers_per_block_count`:                        2,
+		`cybercar_rpc_duration_seconds_count{method="eth_getLogs"}`: 1,
+	} {
+		if m[name] != want {
+			t.Errorf("%s = %v, want %v", name, m[name], want)
+		}
+	}
+}
+
+// collection answers the token calls of the contract with tokens, and its
+// Transfer logs with transfers, one block each.
+type collection struct {
+	Backend
+	contract  common.Address
+	tokens    map[int64]common.Address
+	ids       []int64
+	transfers [][3]int64 // from, to, id; accounts by index in accounts
+	accounts  []common.Address
+}
+
+func (c *collection) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if call.To == nil || *call.To != c.contract {
+		return c.Backend.CallContract(ctx, call, blockNumber)
+	}
+	method, err := carABI.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "totalSupply":
+		return method.Outputs.Pack(big.NewInt(int64(len(c.ids))))
+	case "tokenByIndex":
+		return method.Outputs.Pack(big.NewInt(c.ids[args[0].(*big.Int).Int64()]))
+	case "ownerOf":
+		if owner, ok := c.tokens[args[0].(*big.Int).Int64()]; ok {
+			return method.Outputs.Pack(owner)
+		}
+	}
+	return nil, errors.New("execution reverted")
+}
+
+func (c *collection) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for i, t := range c.transfers {
+		block := uint64(i + 1)
+		if block < q.FromBlock.Uint64() || block > q.ToBlock.Uint64() {
+			continue
+		}
+		logs = append(logs, types.Log{Address: c.contract, BlockNumber: block, Topics: []common.Hash{
+			carABI.Events["Transfer"].ID,
+			common.BytesToHash(c.accounts[t[0]].Bytes()),
+			common.BytesToHash(c.accounts[t[1]].Bytes()),
+			common.BigToHash(big.NewInt(t[2])),
+		}})
+	}
+	return logs, nil
+}
+
+func TestExporterSeedHolders(t *testing.T) {
+	zero, alice, bob := common.Address{}, common.HexToAddress("0x0a"), common.HexToAddress("0x0b")
+	for _, deployBlock := range []uint64{0, 1} {
+		sim, cfg := newBare(t)
+		cfg.DeployBlock = deployBlock
+		// ids 7 and 9 minted to alice, 3 to bob, 7 given to bob, 9 burnt
+		c := &collection{
+			Backend:   sim,
+			contract:  common.HexToAddress(cfg.Contract),
+			tokens:    map[int64]common.Address{3: bob, 7: bob},
+			ids:       []int64{3, 7},
+			accounts:  []common.Address{zero, alice, bob},
+			transfers: [][3]int64{{0, 1, 7}, {0, 2, 3}, {0, 1, 9}, {1, 2, 7}, {1, 0, 9}},
+		}
+		for i := 0; i < len(c.transfers); i++ {
+			sim.Commit()
+		}
+		n := NewWithBackend(cfg, c)
+		ctx := context.Background()
+		if err := n.Init(ctx); err != nil {
+			t.Fatal(err)
+		}
+		e, err := n.NewExporter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := n.PinBlock(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = e.seedHolders(ctx, block); err != nil {
+			t.Fatal(err)
+		}
+		if len(e.owners) != 2 || e.owners["3"] != bob || e.owners["7"] != bob || len(e.balances) != 1 {
+			t.Errorf("deploy block %d: owners %v, balances %v; want 3 and 7 held by bob", deployBlock, e.owners, e.balances)
+		}
+		if m := scrape(t, e); m["cybercar_holders"] != 1 {
+			t.Errorf("deploy block %d: holders = %v, want 1", deployBlock, m["cybercar_holders"])
+		}
+	}
+}
+
+// chainBackend tells its chain id, as ethclient does.
+type chainBackend struct {
+	Backend
+	id int64
+}
+
+func (b chainBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(b.id), nil
+}
+
+func TestObservedChainID(t *testing.T) {
+	ctx := context.Background()
+	for _, id := range []int64{0, 5} {
+		sim, cfg := newBare(t)
+		var backend Backend = sim
+		if id != 0 {
+			backend = chainBackend{Backend: sim, id: id}
+			cfg.ChainID = uint64(id)
+		}
+		n := NewWithBackend(cfg, backend)
+		if err := n.Init(ctx); err != nil {
+			t.Fatal(err)
+		}
+		e, err := n.NewExporter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := big.NewInt(id)
+		if id == 0 {
+			want = big.NewInt(testChainID)
+		}
+		if got, err := n.chainID(ctx); err != nil || got.Cmp(want) != 0 {
+			t.Errorf("chain id = %v, %v; want %s", got, err, want)
+		}
+		if m := scrape(t, e); (m[`cybercar_rpc_duration_seconds_count{method="eth_chainId"}`] == 1) != (id != 0) {
+			t.Errorf("backend with chain id %d: eth_chainId observed %v times", id, m[`cybercar_rpc_duration_seconds_count{method="eth_chainId"}`])
+		}
+	}
+}
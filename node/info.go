@@ -0,0 +1,111 @@
+package node
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// ERC-165 interface ids of the standards the collection implements.
+var (
+	InterfaceERC721           = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceERC721Metadata   = [4]byte{0x5b, 0x5e, 0x13, 0x9f}
+	InterfaceERC721Enumerable = [4]byte{0x78, 0x0e, 0x9d, 0x63}
+)
+
+// CollectionInfo is the state of the contract at one block.
+type CollectionInfo struct {
+	Contract     common.Address `json:"contract"`
+	Block        *big.Int       `json:"block"`
+	Name         string         `json:"name"`
+	Symbol       string         `json:"symbol"`
+	Version      string         `json:"version"`
+	Owner        common.Address `json:"owner"`
+	Paused       bool           `json:"paused"`
+	Phase        int8           `json:"phase"`
+	Capacity     *big.Int       `json:"capacity"`
+	TotalSupply  *big.Int       `json:"totalSupply"`
+	Reserved     *big.Int       `json:"reserved"`
+	WhitelistCap *big.Int       `json:"whitelistCap"`
+	MintPrice    *big.Int       `json:"mintPrice"`
+	Balance      *big.Int       `json:"balance"`
+
+	ERC721           bool `json:"erc721"`
+	ERC721Metadata   bool `json:"erc721Metadata"`
+	ERC721Enumerable bool `json:"erc721Enumerable"`
+}
+
+// PinBlock returns block, or the latest block number if it is nil, so that a
+// series of calls reads one consistent state.
+func (n *Node) PinBlock(ctx context.Context, block *big.Int) (*big.Int, error) {
+	if block != nil {
+		return block, nil
+	}
+	head, err := n.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		n.Sugar.Errorf("get latest block error: %s", err)
+		return nil, err
+	}
+	return head.Number, nil
+}
+
+// Info reads the collection state at block, the latest block if nil.
+func (n *Node) Info(ctx context.Context, block *big.Int) (*CollectionInfo, error) {
+	block, err := n.PinBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	info := &CollectionInfo{Contract: n.contract, Block: block}
+	fail := func(what string, err error) (*CollectionInfo, error) {
+		n.Sugar.Errorf("read %s error: %s", what, err)
+		return nil, err
+	}
+	if info.Name, err = n.nft.Name(opts); err != nil {
+		return fail("name", err)
+	}
+	if info.Symbol, err = n.nft.Symbol(opts); err != nil {
+		return fail("symbol", err)
+	}
+	if info.Version, err = n.nft.Version(opts); err != nil {
+		return fail("version", err)
+	}
+	if info.Owner, err = n.nft.Owner(opts); err != nil {
+		return fail("owner", err)
+	}
+	if info.Paused, err = n.nft.Paused(opts); err != nil {
+		return fail("paused", err)
+	}
+	if info.Phase, err = n.nft.Phase(opts); err != nil {
+		return fail("phase", err)
+	}
+	if info.Capacity, err = n.nft.Capacity(opts); err != nil {
+		return fail("capacity", err)
+	}
+	if info.TotalSupply, err = n.nft.TotalSupply(opts); err != nil {
+		return fail("totalSupply", err)
+	}
+	if info.Reserved, err = n.nft.Reserved(opts); err != nil {
+		return fail("reserved", err)
+	}
+	if info.WhitelistCap, err = n.nft.WhitelistCap(opts); err != nil {
+		return fail("whitelistCap", err)
+	}
+	if info.MintPrice, err = n.nft.MintPrice(opts); err != nil {
+		return fail("mintPrice", err)
+	}
+	if info.Balance, err = n.backend.BalanceAt(ctx, n.contract, block); err != nil {
+		return fail("balance", err)
+	}
+	if info.ERC721, err = n.nft.SupportsInterface(opts, InterfaceERC721); err != nil {
+		return fail("supportsInterface", err)
+	}
+	if info.ERC721Metadata, err = n.nft.SupportsInterface(opts, InterfaceERC721Metadata); err != nil {
+		return fail("supportsInterface", err)
+	}
+	if info.ERC721Enumerable, err = n.nft.SupportsInterface(opts, InterfaceERC721Enumerable); err != nil {
+		return fail("supportsInterface", err)
+	}
+	return info, nil
+}
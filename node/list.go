@@ -0,0 +1,263 @@
+package node
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListEntry is an accepted address of a list, with its amount.
+type ListEntry struct {
+	Line    int
+	Address common.Address
+	Amount  uint8
+	// Name is the ENS name the row gave in place of the address.
+	Name string
+}
+
+// ListIssue is a row, or a cell of a row, that was rejected or looks suspicious.
+type ListIssue struct {
+	Line     int
+	Value    string
+	Reason   string
+	Rejected bool
+}
+
+func (i ListIssue) String() string {
+	kind := "warning"
+	if i.Rejected {
+		kind = "rejected"
+	}
+	return fmt.Sprintf("line %d: %s %q: %s", i.Line, kind, i.Value, i.Reason)
+}
+
+// AddressList is a validated address list.
+type AddressList struct {
+	Entries []ListEntry
+	Issues  []ListIssue
+}
+
+// ListOptions controls how LoadAddressList treats the rows.
+type ListOptions struct {
+	// Contract is rejected as a list entry, tokens sent there are lost.
+	Contract common.Address
+	// DefaultAmount is the amount of rows without one.
+	DefaultAmount uint8
+	// RequireAmount rejects rows without an amount.
+	RequireAmount bool
+	// Resolve resolves ENS names, they are rejected if it is nil.
+	Resolve func(name string) (common.Address, error)
+}
+
+// listHeaders are the names of the address column skipped as a header.
+var listHeaders = map[string]bool{
+	"address":   true,
+	"addresses": true,
+	"wallet":    true,
+	"account":   true,
+	"owner":     true,
+	"holder":    true,
+	"ens":       true,
+}
+
+// LoadAddressList reads a csv list with one address per row, optionally
+// followed by its amount. A header row naming the address column (see
+// listHeaders), blank cells and blank rows are skipped; any other first row
+// which is not an address nor a name is skipped with a warning.
+// Invalid addresses, bad EIP-55 checksums, the zero address and the contract
+// address are rejected; ENS names are resolved with opts.Resolve; duplicates
+// are dropped with a warning, or rejected if their amount differs.
+func LoadAddressList(filename string, opts ListOptions) (*AddressList, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	list := &AddressList{}
+	seen := make(map[common.Address]ListEntry)
+	first := true
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		var cells []string
+		for _, cell := range record {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		reject := func(value, reason string) {
+			list.Issues = append(list.Issues, ListIssue{Line: line, Value: value, Reason: reason, Rejected: true})
+		}
+		if first {
+			first = false
+			if listHeaders[strings.ToLower(cells[0])] {
+				continue
+			}
+			// an unknown header, or a first address gone wrong: say so
+			if !strings.HasPrefix(strings.ToLower(cells[0]), "0x") && !isHex(cells[0]) && !IsENSName(cells[0]) {
+				list.Issues = append(list.Issues, ListIssue{Line: line, Value: cells[0], Reason: "not an address nor a known header, row skipped"})
+				continue
+			}
+		}
+
+		// address,amount; or the older layout of any number of addresses per row
+		amount, hasAmount := opts.DefaultAmount, false
+		if len(cells) == 2 && isDecimal(cells[1]) {
+			a, err := strconv.ParseUint(cells[1], 10, 8)
+			if err != nil {
+				reject(cells[1], "amount must be 0-255")
+				continue
+			}
+			amount, hasAmount = uint8(a), true
+			cells = cells[:1]
+		}
+		for _, cell := range cells {
+			var address common.Address
+			var reason, name string
+			if IsENSName(cell) {
+				name = cell
+				address, reason = resolveListName(cell, opts.Resolve)
+			} else {
+				address, reason = parseListAddress(cell)
+			}
+			switch {
+			case reason != "":
+				reject(cell, reason)
+				continue
+			case address == (common.Address{}):
+				reject(cell, "zero address")
+				continue
+			case address == opts.Contract:
+				reject(cell, "the contract itself")
+				continue
+			case opts.RequireAmount && !hasAmount:
+				reject(cell, "amount missing")
+				continue
+			}
+			if prev, ok := seen[address]; ok {
+				if prev.Amount != amount {
+					reject(cell, fmt.Sprintf("listed on line %d with amount %d, here %d", prev.Line, prev.Amount, amount))
+				} else {
+					list.Issues = append(list.Issues, ListIssue{Line: line, Value: cell, Reason: fmt.Sprintf("duplicate of line %d, skipped", prev.Line)})
+				}
+				continue
+			}
+			e := ListEntry{Line: line, Address: address, Amount: amount, Name: name}
+			seen[address] = e
+			list.Entries = append(list.Entries, e)
+		}
+	}
+	return list, nil
+}
+
+// parseListAddress parses a hex address, the reason is empty if it is valid.
+func parseListAddress(s string) (common.Address, string) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if !isHex(hex) {
+		return common.Address{}, "not hex"
+	}
+	if len(hex) != 2*common.AddressLength {
+		return common.Address{}, fmt.Sprintf("%d hex digits, want %d", len(hex), 2*common.AddressLength)
+	}
+	address := common.HexToAddress(s)
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && "0x"+hex != address.Hex() {
+		return common.Address{}, "bad EIP-55 checksum, want " + address.Hex()
+	}
+	return address, ""
+}
+
+func resolveListName(name string, resolve func(string) (common.Address, error)) (common.Address, string) {
+	if resolve == nil {
+		return common.Address{}, "ENS names not supported here"
+	}
+	address, err := resolve(name)
+	if err != nil {
+		return common.Address{}, err.Error()
+	}
+	return address, ""
+}
+
+// Rejected counts the rejected rows.
+func (l *AddressList) Rejected() int {
+	count := 0
+	for _, i := range l.Issues {
+		if i.Rejected {
+			count++
+		}
+	}
+	return count
+}
+
+// Addresses returns the accepted addresses.
+func (l *AddressList) Addresses() []common.Address {
+	addresses := make([]common.Address, 0, len(l.Entries))
+	for _, e := range l.Entries {
+		addresses = append(addresses, e.Address)
+	}
+	return addresses
+}
+
+// AmountGroup is the addresses of a list with the same amount.
+type AmountGroup struct {
+	Amount    uint8
+	Addresses []common.Address
+}
+
+// ByAmount groups the accepted addresses by amount, ascending, as the contract
+// takes one amount per call.
+func (l *AddressList) ByAmount() []AmountGroup {
+	groups := make(map[uint8]*AmountGroup)
+	var amounts []int
+	for _, e := range l.Entries {
+		g, ok := groups[e.Amount]
+		if !ok {
+			g = &AmountGroup{Amount: e.Amount}
+			groups[e.Amount] = g
+			amounts = append(amounts, int(e.Amount))
+		}
+		g.Addresses = append(g.Addresses, e.Address)
+	}
+	sort.Ints(amounts)
+	var sorted []AmountGroup
+	for _, a := range amounts {
+		sorted = append(sorted, *groups[uint8(a)])
+	}
+	return sorted
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDecimal(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
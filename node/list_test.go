@@ -0,0 +1,130 @@
+package node
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeList(t *testing.T, lines ...string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "list.csv")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadAddressListHeader(t *testing.T) {
+	const alice = "0x00000000000000000000000000000000000a11ce"
+	for header, warned := range map[string]bool{
+		"address,amount": false,
+		"Wallet":         false,
+		"recipients":     true,
+		"alice":          true,
+	} {
+		list, err := LoadAddressList(writeList(t, header, alice+",2"), ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Entries) != 1 || list.Entries[0].Line != 2 || list.Entries[0].Amount != 2 {
+			t.Errorf("%q: entries = %+v, want alice from line 2", header, list.Entries)
+		}
+		switch {
+		case warned && (len(list.Issues) != 1 || list.Issues[0].Rejected || list.Issues[0].Line != 1):
+			t.Errorf("%q: issues = %v, want a warning for line 1", header, list.Issues)
+		case !warned && len(list.Issues) != 0:
+			t.Errorf("%q: issues = %v, want none", header, list.Issues)
+		}
+	}
+
+	// only the first row may be a header
+	list, err := LoadAddressList(writeList(t, alice, "address"), ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 1 || list.Rejected() != 1 {
+		t.Errorf("entries = %+v, issues = %v, want the second header rejected", list.Entries, list.Issues)
+	}
+}
+
+func TestLoadAddressList(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000c0de0")
+	list, err := LoadAddressList(writeList(t,
+		"# airdrop",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed,1", // checksummed
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed,1", // duplicate, same amount
+		"0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED,3", // duplicate, other amount
+		"0xFB6916095ca1df60bB79Ce92cE3Ea74c37c5d359,2", // bad checksum
+		"0x0000000000000000000000000000000000000000,1", // zero
+		"0x00000000000000000000000000000000000c0de0,1", // the contract
+		"0x1234,1", // short
+		"0x00000000000000000000000000000000000b0b00,300", // amount
+		"",
+		"0x00000000000000000000000000000000000b0b00",
+	), ListOptions{Contract: contract, DefaultAmount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 2 || list.Entries[0].Line != 2 || list.Entries[1].Line != 11 || list.Entries[1].Amount != 4 {
+		t.Fatalf("entries = %+v, want lines 2 and 11", list.Entries)
+	}
+	var lines []int
+	for _, i := range list.Issues {
+		if i.Rejected {
+			lines = append(lines, i.Line)
+		}
+	}
+	if fmt.Sprint(lines) != "[4 5 6 7 8 9]" {
+		t.Errorf("rejected lines %v, want 4 to 9", lines)
+	}
+	if len(list.Issues) != 7 || list.Issues[0].Line != 3 || list.Issues[0].Rejected {
+		t.Errorf("issues = %v, want the duplicate of line 3 warned", list.Issues)
+	}
+	if !strings.Contains(list.Issues[2].Reason, "EIP-55") {
+		t.Errorf("line 5: %s, want a bad checksum", list.Issues[2].Reason)
+	}
+
+	if list, err = LoadAddressList(writeList(t, "0x00000000000000000000000000000000000b0b00"), ListOptions{RequireAmount: true}); err != nil {
+		t.Fatal(err)
+	}
+	if list.Rejected() != 1 {
+		t.Errorf("issues = %v, want the amount missing", list.Issues)
+	}
+}
+
+func TestLoadAddressListENS(t *testing.T) {
+	alice := common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+	file := filepath.Join(t.TempDir(), "list.csv")
+	data := "alice.eth,2\nnobody.eth,1\n0x00000000000000000000000000000000000A11CE,2\n"
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(name string) (common.Address, error) {
+		if name == "alice.eth" {
+			return alice, nil
+		}
+		return common.Address{}, ErrENSNotFound
+	}
+	list, err := LoadAddressList(file, ListOptions{Resolve: resolve})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 1 || list.Entries[0].Address != alice || list.Entries[0].Name != "alice.eth" || list.Entries[0].Line != 1 {
+		t.Fatalf("entries = %+v, want alice.eth from line 1", list.Entries)
+	}
+	if len(list.Issues) != 2 || !list.Issues[0].Rejected || list.Issues[1].Rejected {
+		t.Fatalf("issues = %v, want nobody.eth rejected and the duplicate warned", list.Issues)
+	}
+
+	list, err = LoadAddressList(file, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Rejected() != 2 {
+		t.Fatalf("without a resolver: %d rejected, want 2", list.Rejected())
+	}
+}
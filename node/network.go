@@ -0,0 +1,40 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Network is a named deployment of the contract, e.g. mainnet, goerli or local.
+type Network struct {
+	RPC      string `json:"rpc"`
+	Contract string `json:"contract"`
+	// ChainID is the chain the rpc must be on, Init fails otherwise. It is
+	// required, a profile without it would not pin the chain.
+	ChainID uint64 `json:"chainId"`
+	// DeployBlock is the block the contract was deployed at, where log scans start.
+	DeployBlock uint64 `json:"deployBlock"`
+}
+
+// UseNetwork applies the network profile name onto the config.
+func (c *Config) UseNetwork(name string) error {
+	network, ok := c.Networks[name]
+	if !ok {
+		var names []string
+		for n := range c.Networks {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("network %s not found in config, have: %s", name, strings.Join(names, ", "))
+	}
+	if network.ChainID == 0 {
+		return fmt.Errorf("network %s has no chainId", name)
+	}
+	c.Network = name
+	c.RPC = network.RPC
+	c.Contract = network.Contract
+	c.ChainID = network.ChainID
+	c.DeployBlock = network.DeployBlock
+	return nil
+}
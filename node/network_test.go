@@ -0,0 +1,43 @@
+package node
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUseNetwork(t *testing.T) {
+	cfg := Config{Networks: map[string]Network{
+		"goerli": {RPC: "https://goerli.example", Contract: "0x00000000000000000000000000000000000c0de0", ChainID: 5, DeployBlock: 7},
+		"local":  {RPC: "http://localhost:8545"},
+	}}
+	if err := cfg.UseNetwork("goerli"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Network != "goerli" || cfg.RPC != "https://goerli.example" || cfg.ChainID != 5 || cfg.DeployBlock != 7 {
+		t.Errorf("config %+v, want the goerli profile", cfg)
+	}
+	if err := cfg.UseNetwork("local"); err == nil || !strings.Contains(err.Error(), "chainId") {
+		t.Errorf("profile without chainId: err = %v", err)
+	}
+	if err := cfg.UseNetwork("mainnet"); err == nil || !strings.Contains(err.Error(), "goerli, local") {
+		t.Errorf("unknown profile: err = %v, want the known ones listed", err)
+	}
+	if err := cfg.validate(false, false); err == nil || !strings.Contains(err.Error(), "networks.local.chainId missing") {
+		t.Errorf("validate: err = %v, want networks.local.chainId missing", err)
+	}
+}
+
+func TestChainIDMismatch(t *testing.T) {
+	for network, want := range map[string]string{
+		"":       "but the config expects chain 5",
+		"goerli": "but network goerli expects chain 5",
+	} {
+		sim, cfg := newBare(t)
+		cfg.ChainID, cfg.Network = 5, network
+		n := NewWithBackend(cfg, chainBackend{Backend: sim, id: 1})
+		if err := n.Init(context.Background()); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("network %q: err = %v, want %q", network, err, want)
+		}
+	}
+}
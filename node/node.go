@@ -2,19 +2,19 @@ package node
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/cybercar-nft/go-cybercar/cyber"
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
-	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
 	"github.com/xyths/hs"
 	"go.uber.org/zap"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,8 +22,48 @@ type Config struct {
 	Log      hs.LogConf `json:"log"`
 	RPC      string     `json:"rpc"`
 	Contract string     `json:"contract"`
-	Mnemonic string     `json:"mnemonic"`
-	Account  int        `json:"account"`
+	// ChainID pins the chain of the rpc when not 0, see Network.
+	ChainID     uint64 `json:"chainId"`
+	DeployBlock uint64 `json:"deployBlock"`
+	// Network is the default profile in Networks, overriding the fields above.
+	Network  string             `json:"network"`
+	Networks map[string]Network `json:"networks"`
+	// ENSRegistry overrides the ENS registry, for chains without the canonical one.
+	ENSRegistry string `json:"ensRegistry"`
+
+	Mnemonic string `json:"mnemonic"`
+	Account  int    `json:"account"`
+	// DerivationPath is the hd path template of the accounts, see DerivationPaths.
+	DerivationPath string `json:"derivationPath"`
+
+	// Signer is the endpoint (IPC path or http url) of an external clef-compatible
+	// signer. When set, it is used in place of the mnemonic.
+	Signer string `json:"signer"`
+	// SignerAccount picks the external signer account, defaults to the Account-th
+	// one listed by the signer.
+	SignerAccount string `json:"signerAccount"`
+	// From is the account to prepare unsigned transactions for, when neither
+	// the mnemonic nor an external signer is available on this machine.
+	From string `json:"from"`
+	// ChunkSize limits the addresses per addAirdrop/addWhitelist transaction, 0 means no limit.
+	ChunkSize int `json:"chunkSize"`
+
+	// BumpAfter is the seconds a sent transaction may stay pending before it is
+	// replaced with higher fees, 0 disables the automatic fee bump.
+	BumpAfter int `json:"bumpAfter"`
+	// BumpPercent is the fee increase of each replacement, default 12.
+	BumpPercent int `json:"bumpPercent"`
+	// MaxBumps limits the automatic replacements of one transaction, default 3.
+	MaxBumps int `json:"maxBumps"`
+
+	// Confirmations is the block depth to wait for after sending, default 1.
+	Confirmations int `json:"confirmations"`
+	// WaitTimeout is the seconds to wait for a sent transaction, 0 for no limit.
+	WaitTimeout int `json:"waitTimeout"`
+
+	// Notify sends the admin transactions and the contract events to DingTalk,
+	// Slack or webhooks.
+	Notify NotifyConfig `json:"notify"`
 }
 
 type Node struct {
@@ -31,11 +71,27 @@ type Node struct {
 
 	Sugar *zap.SugaredLogger
 
-	wallet  *hdwallet.Wallet
-	account accounts.Account
-
-	ec  *ethclient.Client
-	nft *cyber.Car
+	readOnly      bool
+	deployer      bool
+	signerOnce    sync.Once
+	signerErr     error
+	signer        Signer
+	nonces        *NonceManager
+	unsignedOut   string
+	unsignedCount int
+
+	backend  Backend
+	contract common.Address
+	nft      *cyber.Car
+	raw      *cyber.CarRaw
+	ens      *ENS
+
+	safe     *SafeBatch
+	progress func(WaitStatus)
+	notifier *Notifier
+
+	dryRun        bool
+	dryRunReports []DryRunReport
 }
 
 func New(cfg Config) *Node {
@@ -44,7 +100,41 @@ func New(cfg Config) *Node {
 	}
 }
 
+// NewReadOnly returns a Node for queries, which never touches the mnemonic or
+// the signer, so it runs on machines without any secrets.
+func NewReadOnly(cfg Config) *Node {
+	return &Node{
+		cfg:      cfg,
+		readOnly: true,
+	}
+}
+
+// NewDeployer returns a Node for deploying the contract, the config may have
+// no contract yet. It is bound to the contract once deployed, see Deploy.
+func NewDeployer(cfg Config) *Node {
+	return &Node{
+		cfg:      cfg,
+		deployer: true,
+	}
+}
+
+// NewWithBackend returns a Node on backend instead of dialing Config.RPC, e.g.
+// a simulated backend in tests. The config is not validated, it comes from
+// code rather than a file.
+func NewWithBackend(cfg Config, backend Backend) *Node {
+	return &Node{
+		cfg:     cfg,
+		backend: backend,
+	}
+}
+
 func (n *Node) Init(ctx context.Context) error {
+	// the key material is checked when it is loaded, see signerAccount
+	if n.backend == nil {
+		if err := n.cfg.validate(false, !n.deployer); err != nil {
+			return err
+		}
+	}
 	l, err := hs.NewZapLogger(n.cfg.Log)
 	if err != nil {
 		return err
@@ -52,182 +142,232 @@ func (n *Node) Init(ctx context.Context) error {
 	n.Sugar = l.Sugar()
 	n.Sugar.Info("logger initialized")
 
-	mnemonic, err := loadMnemonic(n.cfg.Mnemonic)
-	if err != nil {
-		n.Sugar.Errorf("load mnemonic error: %s", err)
-		return err
+	if n.backend == nil {
+		if n.backend, err = ethclient.DialContext(ctx, n.cfg.RPC); err != nil {
+			n.Sugar.Errorf("connect rpc error: %s", err)
+			return err
+		}
+		n.Sugar.Info("dial success")
 	}
-
-	n.wallet, err = hdwallet.NewFromMnemonic(mnemonic)
-	if err != nil {
-		n.Sugar.Errorf("new hd wallet error: %s", err)
-		return err
+	if n.cfg.ChainID != 0 {
+		chainId, err := n.chainID(ctx)
+		if err != nil {
+			n.Sugar.Errorf("Get chainId error: %s", err)
+			return err
+		}
+		if chainId.Uint64() != n.cfg.ChainID {
+			n.Sugar.Errorf("chain id mismatch, rpc %d, config %d", chainId, n.cfg.ChainID)
+			source := "the config"
+			if n.cfg.Network != "" {
+				source = "network " + n.cfg.Network
+			}
+			return fmt.Errorf("rpc %s is on chain %s, but %s expects chain %d", n.cfg.RPC, chainId, source, n.cfg.ChainID)
+		}
 	}
-
-	path := hdwallet.MustParseDerivationPath(fmt.Sprintf("m/44'/60'/0'/0/%d", n.cfg.Account))
-	n.account, err = n.wallet.Derive(path, false)
-	if err != nil {
-		n.Sugar.Errorf("derive account error: %s", err)
+	if err = n.bind(common.HexToAddress(n.cfg.Contract)); err != nil {
 		return err
 	}
-	n.Sugar.Info("wallet initialized")
-
-	n.ec, err = ethclient.DialContext(ctx, n.cfg.RPC)
-	if err != nil {
-		n.Sugar.Errorf("connect rpc error: %s", err)
-		return err
+	registry := ENSRegistry
+	if n.cfg.ENSRegistry != "" {
+		registry = common.HexToAddress(n.cfg.ENSRegistry)
 	}
-	n.Sugar.Info("dial success")
-	n.nft, err = cyber.NewCar(common.HexToAddress(n.cfg.Contract), n.ec)
+	n.ens = NewENS(registry, n.backend)
+	if len(n.cfg.Notify.Channels) > 0 && n.notifier == nil {
+		if n.notifier, err = NewNotifier(n.cfg.Notify); err != nil {
+			n.Sugar.Errorf("notifier error: %s", err)
+			return err
+		}
+	}
+	n.Sugar.Info("initialize success")
+	return nil
+}
+
+// bind points the node at the contract address.
+func (n *Node) bind(contract common.Address) error {
+	nft, err := cyber.NewCar(contract, n.backend)
 	if err != nil {
 		n.Sugar.Errorf("New Car error: %s", err)
 		return err
 	}
-	n.Sugar.Info("initialize success")
+	n.contract = contract
+	n.nft = nft
+	n.raw = &cyber.CarRaw{Contract: nft}
 	return nil
 }
 
-type Quota struct {
-	Minted uint8
-	Cap    uint8
+// Config returns the config the node was created with.
+func (n *Node) Config() Config {
+	return n.cfg
 }
 
-func (n *Node) AirdropQuota(ctx context.Context, owner common.Address) (Quota, error) {
-	return n.nft.AirdropQuota(&bind.CallOpts{Context: ctx}, owner)
+// Contract returns the address of the contract.
+func (n *Node) Contract() common.Address {
+	return n.contract
 }
 
-func (n *Node) Paused(ctx context.Context) (bool, error) {
-	return n.nft.Paused(&bind.CallOpts{Context: ctx})
-}
-
-func (n *Node) Phase(ctx context.Context) (int8, error) {
-	return n.nft.Phase(&bind.CallOpts{Context: ctx})
+// signerAccount loads the signer the first time a transaction is to be sent,
+// so commands which only read never load the wallet.
+func (n *Node) signerAccount() (Signer, error) {
+	if n.readOnly {
+		return nil, ErrReadOnly
+	}
+	n.signerOnce.Do(func() {
+		if n.signerErr = n.initSigner(); n.signerErr == nil && n.signer != nil {
+			n.nonces = NewNonceManager(n.signer.Address(), n.backend, n.Sugar)
+		}
+	})
+	if n.signerErr != nil {
+		return nil, n.signerErr
+	}
+	if n.signer == nil {
+		return nil, ErrNoSigner
+	}
+	return n.signer, nil
 }
 
-func (n *Node) MintQuota(ctx context.Context, owner common.Address) (Quota, error) {
-	return n.nft.MintQuota(&bind.CallOpts{Context: ctx}, owner)
-}
+func (n *Node) initSigner() error {
+	if n.cfg.Signer != "" {
+		s, err := NewExternalSigner(n.cfg.Signer, n.cfg.SignerAccount, n.cfg.Account)
+		if err != nil {
+			n.Sugar.Errorf("connect external signer error: %s", err)
+			return err
+		}
+		n.signer = s
+		n.Sugar.Infof("external signer initialized, account %s", s.Address())
+		return nil
+	}
 
-func (n *Node) AddAirdrop(ctx context.Context, owners []common.Address, amount uint8) error {
-	for _, owner := range owners {
-		n.Sugar.Infof("AddAirdrop for %s", owner.String())
+	if n.cfg.Mnemonic == "" {
+		if n.cfg.From == "" {
+			n.Sugar.Info("no signer configured")
+			return nil
+		}
+		if !common.IsHexAddress(n.cfg.From) {
+			return fmt.Errorf("bad from address %s", n.cfg.From)
+		}
+		n.signer = addressSigner(common.HexToAddress(n.cfg.From))
+		n.Sugar.Infof("watch-only account %s", n.cfg.From)
+		return nil
 	}
-	chainId, err := n.ec.ChainID(ctx)
+	s, err := NewMnemonicSigner(n.cfg.Mnemonic, n.cfg.DerivationPath, n.cfg.Account)
 	if err != nil {
-		n.Sugar.Errorf("Get chainId error: %s", err)
+		n.Sugar.Errorf("load wallet error: %s", err)
 		return err
 	}
-	privateKey, err := n.wallet.PrivateKey(n.account)
+	n.signer = s
+	n.Sugar.Info("wallet initialized")
+	return nil
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+// transactOpts prepares the options for sending a transaction from the signer account.
+func (n *Node) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	signer, err := n.signerAccount()
 	if err != nil {
-		n.Sugar.Errorf("Get transactor error: %s", err)
-		return err
+		return nil, err
 	}
-	nonce, err := n.ec.NonceAt(ctx, n.account.Address, nil)
+	chainId, err := n.chainID(ctx)
 	if err != nil {
-		n.Sugar.Errorf("Get nonce error: %s", err)
-		return err
+		n.Sugar.Errorf("Get chainId error: %s", err)
+		return nil, err
+	}
+	from := signer.Address()
+	auth := &bind.TransactOpts{
+		From: from,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(tx, chainId)
+		},
+		Context: ctx,
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0)      // in wei
 	auth.GasLimit = uint64(6721975) // in units
-	gasPrice, err := n.ec.SuggestGasPrice(ctx)
+	gasPrice, err := n.backend.SuggestGasPrice(ctx)
 	if err != nil {
 		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
-		return err
+		return nil, err
 	}
 	auth.GasPrice = gasPrice
-
-	tx, err := n.nft.AddAirdrop(auth, owners, amount)
+	nonce, err := n.nonces.Next(ctx)
 	if err != nil {
-		n.Sugar.Errorf("AddAirdrop error: %s", err)
-		return err
+		n.Sugar.Errorf("Get nonce error: %s", err)
+		return nil, err
 	}
-	pending := true
-	for pending {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Second):
-			_, isPending, err := n.ec.TransactionByHash(ctx, tx.Hash())
-			if err != nil {
-				return err
-			}
-			if !isPending {
-				pending = false // break `for`
-			}
+	auth.Nonce = new(big.Int).SetUint64(nonce)
+	return auth, nil
+}
+
+type Quota struct {
+	Minted uint8
+	Cap    uint8
+}
+
+func (n *Node) AirdropQuota(ctx context.Context, owner common.Address) (Quota, error) {
+	return n.nft.AirdropQuota(&bind.CallOpts{Context: ctx}, owner)
+}
+
+func (n *Node) Owner(ctx context.Context) (common.Address, error) {
+	return n.nft.Owner(&bind.CallOpts{Context: ctx})
+}
+
+// CheckOwner makes sure the admin calls come from the contract owner, so they
+// don't revert and waste gas. The account checked is as, or the signer account
+// if as is zero, e.g. as is the Safe when the calls are executed by a Safe.
+func (n *Node) CheckOwner(ctx context.Context, as common.Address) error {
+	if as == (common.Address{}) {
+		signer, err := n.signerAccount()
+		if err != nil {
+			return err
 		}
+		as = signer.Address()
 	}
-	receipt, err := n.ec.TransactionReceipt(ctx, tx.Hash())
+	owner, err := n.Owner(ctx)
 	if err != nil {
+		n.Sugar.Errorf("get owner error: %s", err)
 		return err
 	}
-	if receipt.Status == 0 {
-		msg := fmt.Sprintf("transaction reverted, hash %s", receipt.TxHash.String())
-		return errors.New(msg)
+	if owner != as {
+		return fmt.Errorf("%w: account %s is not the contract owner %s", ErrNotOwner, as.Hex(), owner.Hex())
 	}
-
+	n.Sugar.Infof("account %s is the contract owner", as.Hex())
 	return nil
 }
 
-func (n *Node) AddWhitelist(ctx context.Context, owners []common.Address, amount uint8) error {
-	chainId, err := n.ec.ChainID(ctx)
-	if err != nil {
-		n.Sugar.Errorf("Get chainId error: %s", err)
-		return err
-	}
-	privateKey, err := n.wallet.PrivateKey(n.account)
+func (n *Node) Paused(ctx context.Context) (bool, error) {
+	return n.nft.Paused(&bind.CallOpts{Context: ctx})
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
-	if err != nil {
-		n.Sugar.Errorf("Get transactor error: %s", err)
-		return err
-	}
-	nonce, err := n.ec.NonceAt(ctx, n.account.Address, nil)
-	if err != nil {
-		n.Sugar.Errorf("Get nonce error: %s", err)
-		return err
-	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)      // in wei
-	auth.GasLimit = uint64(6721975) // in units
-	gasPrice, err := n.ec.SuggestGasPrice(ctx)
-	if err != nil {
-		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
-		return err
-	}
-	auth.GasPrice = gasPrice
+func (n *Node) Balance(ctx context.Context, account common.Address) (*big.Int, error) {
+	return n.backend.BalanceAt(ctx, account, nil)
+}
 
-	tx, err := n.nft.AddWhitelist(auth, owners, amount)
-	if err != nil {
-		n.Sugar.Errorf("AddWhitelist error: %s", err)
-		return err
+func (n *Node) Phase(ctx context.Context) (int8, error) {
+	return n.nft.Phase(&bind.CallOpts{Context: ctx})
+}
+
+func (n *Node) MintQuota(ctx context.Context, owner common.Address) (Quota, error) {
+	return n.nft.MintQuota(&bind.CallOpts{Context: ctx}, owner)
+}
+
+func (n *Node) AddAirdrop(ctx context.Context, owners []common.Address, amount uint8) error {
+	for _, owner := range owners {
+		n.Sugar.Infof("AddAirdrop for %s", owner.String())
 	}
-	pending := true
-	for pending {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Second):
-			_, isPending, err := n.ec.TransactionByHash(ctx, tx.Hash())
-			if err != nil {
-				return err
-			}
-			if !isPending {
-				pending = false // break `for`
-			}
+	for _, chunk := range n.chunks(owners) {
+		if err := n.transact(ctx, "addAirdrop", chunk, amount); err != nil {
+			return err
 		}
 	}
-	receipt, err := n.ec.TransactionReceipt(ctx, tx.Hash())
-	if err != nil {
-		return err
-	}
-	if receipt.Status == 0 {
-		msg := fmt.Sprintf("transaction reverted, hash %s", receipt.TxHash.String())
-		return errors.New(msg)
-	}
+	return nil
+}
 
+func (n *Node) AddWhitelist(ctx context.Context, owners []common.Address, amount uint8) error {
+	for _, chunk := range n.chunks(owners) {
+		if err := n.transact(ctx, "addWhitelist", chunk, amount); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -241,63 +381,7 @@ func (n *Node) Pause(ctx context.Context) error {
 		n.Sugar.Info("already paused")
 		return nil
 	}
-	chainId, err := n.ec.ChainID(ctx)
-	if err != nil {
-		n.Sugar.Errorf("Get chainId error: %s", err)
-		return err
-	}
-	privateKey, err := n.wallet.PrivateKey(n.account)
-
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
-	if err != nil {
-		n.Sugar.Errorf("Get transactor error: %s", err)
-		return err
-	}
-	nonce, err := n.ec.NonceAt(ctx, n.account.Address, nil)
-	if err != nil {
-		n.Sugar.Errorf("Get nonce error: %s", err)
-		return err
-	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)      // in wei
-	auth.GasLimit = uint64(6721975) // in units
-	gasPrice, err := n.ec.SuggestGasPrice(ctx)
-	if err != nil {
-		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
-		return err
-	}
-	auth.GasPrice = gasPrice
-
-	tx, err := n.nft.Pause(auth)
-	if err != nil {
-		n.Sugar.Errorf("Pause error: %s", err)
-		return err
-	}
-	pending := true
-	for pending {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Second):
-			_, isPending, err := n.ec.TransactionByHash(ctx, tx.Hash())
-			if err != nil {
-				return err
-			}
-			if !isPending {
-				pending = false // break `for`
-			}
-		}
-	}
-	receipt, err := n.ec.TransactionReceipt(ctx, tx.Hash())
-	if err != nil {
-		return err
-	}
-	if receipt.Status == 0 {
-		msg := fmt.Sprintf("transaction reverted, hash %s", receipt.TxHash.String())
-		return errors.New(msg)
-	}
-
-	return nil
+	return n.transact(ctx, "pause")
 }
 
 func (n *Node) Unpause(ctx context.Context) error {
@@ -310,123 +394,141 @@ func (n *Node) Unpause(ctx context.Context) error {
 		n.Sugar.Info("already non-paused")
 		return nil
 	}
-	chainId, err := n.ec.ChainID(ctx)
-	if err != nil {
-		n.Sugar.Errorf("Get chainId error: %s", err)
-		return err
-	}
-	privateKey, err := n.wallet.PrivateKey(n.account)
+	return n.transact(ctx, "unpause")
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
-	if err != nil {
-		n.Sugar.Errorf("Get transactor error: %s", err)
-		return err
+func (n *Node) SetPhase(ctx context.Context, newPhase int8) error {
+	return n.transact(ctx, "setPhase", newPhase)
+}
+
+// chunks splits a long address list by Config.ChunkSize, one transaction per chunk.
+func (n *Node) chunks(owners []common.Address) [][]common.Address {
+	size := n.cfg.ChunkSize
+	if size <= 0 || len(owners) <= size {
+		return [][]common.Address{owners}
 	}
-	nonce, err := n.ec.NonceAt(ctx, n.account.Address, nil)
-	if err != nil {
-		n.Sugar.Errorf("Get nonce error: %s", err)
-		return err
+	var chunks [][]common.Address
+	for len(owners) > size {
+		chunks = append(chunks, owners[:size])
+		owners = owners[size:]
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)      // in wei
-	auth.GasLimit = uint64(6721975) // in units
-	gasPrice, err := n.ec.SuggestGasPrice(ctx)
-	if err != nil {
-		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
-		return err
-	}
-	auth.GasPrice = gasPrice
+	return append(chunks, owners)
+}
 
-	tx, err := n.nft.Unpause(auth)
-	if err != nil {
-		n.Sugar.Errorf("Pause error: %s", err)
-		return err
+// transact calls the contract method and waits until the transaction is mined.
+// In dry-run mode the call is only simulated. When a Safe batch is open, the
+// call is appended to the batch instead; when an unsigned output file is set,
+// the prepared transaction is written there.
+func (n *Node) transact(ctx context.Context, method string, params ...interface{}) error {
+	if n.dryRun {
+		return n.simulate(ctx, method, params...)
+	}
+	if n.safe != nil {
+		if err := n.safe.Add(n.contract, method, params...); err != nil {
+			n.Sugar.Errorf("%s error: %s", method, err)
+			return err
+		}
+		n.Sugar.Infof("%s added to safe batch", method)
+		return nil
 	}
-	pending := true
-	for pending {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Second):
-			_, isPending, err := n.ec.TransactionByHash(ctx, tx.Hash())
-			if err != nil {
-				return err
-			}
-			if !isPending {
-				pending = false // break `for`
-			}
+	if n.unsignedOut != "" {
+		auth, err := n.transactOpts(ctx)
+		if err != nil {
+			return err
 		}
+		return n.writeUnsigned(ctx, auth, method, params...)
 	}
-	receipt, err := n.ec.TransactionReceipt(ctx, tx.Hash())
+
+	tx, err := n.submit(ctx, method, params...)
 	if err != nil {
 		return err
 	}
-	if receipt.Status == 0 {
-		msg := fmt.Sprintf("transaction reverted, hash %s", receipt.TxHash.String())
-		return errors.New(msg)
-	}
-
-	return nil
+	_, err = n.waitNotified(ctx, method, tx)
+	return err
 }
 
-func (n *Node) SetPhase(ctx context.Context, newPhase int8) error {
-	chainId, err := n.ec.ChainID(ctx)
+// submit sends the method call, resyncing the nonce and trying once more if
+// the node rejects it.
+func (n *Node) submit(ctx context.Context, method string, params ...interface{}) (*types.Transaction, error) {
+	tx, err := n.send(ctx, method, params...)
+	if err != nil && isNonceError(err) {
+		n.Sugar.Infof("%s nonce rejected, resync and retry: %s", method, err)
+		n.nonces.Resync()
+		tx, err = n.send(ctx, method, params...)
+	}
 	if err != nil {
-		n.Sugar.Errorf("Get chainId error: %s", err)
-		return err
+		n.Sugar.Errorf("%s error: %s", method, err)
+		return nil, err
 	}
-	privateKey, err := n.wallet.PrivateKey(n.account)
+	n.Sugar.Infof("%s sent, tx %s, nonce %d", method, tx.Hash().String(), tx.Nonce())
+	return tx, nil
+}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+// send signs and sends the method call with a fresh nonce, releasing the nonce if it fails.
+func (n *Node) send(ctx context.Context, method string, params ...interface{}) (*types.Transaction, error) {
+	auth, err := n.transactOpts(ctx)
 	if err != nil {
-		n.Sugar.Errorf("Get transactor error: %s", err)
-		return err
+		return nil, err
 	}
-	nonce, err := n.ec.NonceAt(ctx, n.account.Address, nil)
+	tx, err := n.raw.Transact(auth, method, params...)
 	if err != nil {
-		n.Sugar.Errorf("Get nonce error: %s", err)
-		return err
+		n.nonces.Release(auth.Nonce.Uint64())
+		return nil, err
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0)      // in wei
-	auth.GasLimit = uint64(6721975) // in units
-	gasPrice, err := n.ec.SuggestGasPrice(ctx)
+	return tx, nil
+}
+
+// waitMined waits until tx, or one of its fee-bumped replacements, is mined
+// and confirmed. Replacements are sent per the fee bump policy, see Config.BumpAfter.
+func (n *Node) waitMined(ctx context.Context, tx *types.Transaction) error {
+	_, err := n.waitReceipt(ctx, tx)
+	return err
+}
+
+// waitReceipt is waitMined returning the receipt of the transaction mined,
+// also along with ErrReverted.
+func (n *Node) waitReceipt(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	chainId, err := n.chainID(ctx)
 	if err != nil {
-		n.Sugar.Errorf("SuggestGasPrice error: %s", err)
-		return err
+		return nil, err
 	}
-	auth.GasPrice = gasPrice
-
-	tx, err := n.nft.SetPhase(auth, newPhase)
+	from, err := types.Sender(types.LatestSignerForChainID(chainId), tx)
 	if err != nil {
-		n.Sugar.Errorf("AddWhitelist error: %s", err)
-		return err
+		return nil, err
 	}
-	pending := true
-	for pending {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(time.Second):
-			_, isPending, err := n.ec.TransactionByHash(ctx, tx.Hash())
+	w := &Waiter{
+		Backend:       n.backend,
+		Confirmations: uint64(n.cfg.Confirmations),
+		Timeout:       time.Duration(n.cfg.WaitTimeout) * time.Second,
+		Progress:      n.progress,
+		Bump: func(ctx context.Context, last *types.Transaction) (*types.Transaction, error) {
+			bumped, err := n.replace(ctx, last, false)
 			if err != nil {
-				return err
+				n.Sugar.Warnf("fee bump of tx %s failed, keep waiting: %s", last.Hash().String(), err)
+				return nil, err
 			}
-			if !isPending {
-				pending = false // break `for`
-			}
-		}
+			n.Sugar.Infof("tx %s pending too long, replaced by %s", last.Hash().String(), bumped.Hash().String())
+			return bumped, nil
+		},
+		BumpAfter: time.Duration(n.cfg.BumpAfter) * time.Second,
+		MaxBumps:  n.maxBumps(),
 	}
-	receipt, err := n.ec.TransactionReceipt(ctx, tx.Hash())
-	if err != nil {
-		return err
+	if n.readOnly {
+		// e.g. a broadcast of a transaction signed elsewhere, there is no key to bump it
+		w.Bump = nil
 	}
-	if receipt.Status == 0 {
-		msg := fmt.Sprintf("transaction reverted, hash %s", receipt.TxHash.String())
-		return errors.New(msg)
+	receipt, err := w.Wait(ctx, from, tx)
+	if err != nil {
+		n.Sugar.Errorf("wait tx %s error: %s", tx.Hash().String(), err)
+		return receipt, err
 	}
+	n.Sugar.Infof("tx %s confirmed in block %s", receipt.TxHash.String(), receipt.BlockNumber)
+	return receipt, nil
+}
 
-	return nil
+// SetProgress sets the function reporting the progress of waiting transactions.
+func (n *Node) SetProgress(progress func(WaitStatus)) {
+	n.progress = progress
 }
 
 func loadMnemonic(filename string) (string, error) {
@@ -440,5 +542,5 @@ func loadMnemonic(filename string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(b), nil
+	return strings.TrimSpace(string(b)), nil
 }
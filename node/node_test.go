@@ -0,0 +1,271 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/cybercar-nft/go-cybercar/cyber/cybertest"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/xyths/hs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	_ Backend = (*ethclient.Client)(nil)
+	_ Backend = (*backends.SimulatedBackend)(nil)
+)
+
+const (
+	testMnemonic = "test test test test test test test test test test test junk"
+	testChainID  = 1337
+)
+
+// autoMine mines every transaction as it is sent.
+type autoMine struct {
+	*backends.SimulatedBackend
+}
+
+func (b autoMine) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := b.SimulatedBackend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	b.Commit()
+	return nil
+}
+
+// harness is the contract deployed on a simulated chain, with the Nodes of
+// the owner (account 0) and of another account (account 1).
+type harness struct {
+	backend  *backends.SimulatedBackend
+	contract common.Address
+	owner    *Node
+	other    *Node
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	cybertest.Skip(t)
+	mnemonic := filepath.Join(t.TempDir(), "mnemonic")
+	if err := os.WriteFile(mnemonic, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deployer, _ := crypto.GenerateKey()
+	auth, err := bind.NewKeyedTransactorWithChainID(deployer, big.NewInt(testChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ether := new(big.Int).Lsh(big.NewInt(1), 100)
+	alloc := core.GenesisAlloc{auth.From: {Balance: ether}}
+	for account := 0; account < 2; account++ {
+		s, err := NewMnemonicSigner(mnemonic, "", account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alloc[s.Address()] = core.GenesisAccount{Balance: ether}
+	}
+	h := &harness{backend: backends.NewSimulatedBackend(alloc, 30_000_000)}
+	t.Cleanup(func() { _ = h.backend.Close() })
+	if h.contract, _, err = cybertest.Deploy(auth, h.backend); err != nil {
+		t.Fatal(err)
+	}
+
+	newNode := func(account int) *Node {
+		n := NewWithBackend(Config{
+			Log:      hs.LogConf{Level: "error", Outputs: []string{"stderr"}, Errors: []string{"stderr"}},
+			Contract: h.contract.Hex(),
+			ChainID:  testChainID,
+			Mnemonic: mnemonic,
+			Account:  account,
+		}, autoMine{h.backend})
+		if err := n.Init(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	h.owner, h.other = newNode(0), newNode(1)
+	if err = h.owner.transact(context.Background(), "initialize"); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// newBare returns a simulated chain funding account 0 of the test mnemonic,
+// and the config of its node. No contract is deployed at the contract
+// address: calls there succeed and do nothing.
+func newBare(t *testing.T) (*backends.SimulatedBackend, Config) {
+	t.Helper()
+	mnemonic := filepath.Join(t.TempDir(), "mnemonic")
+	if err := os.WriteFile(mnemonic, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMnemonicSigner(mnemonic, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{s.Address(): {Balance: new(big.Int).Lsh(big.NewInt(1), 100)}}, 30_000_000)
+	t.Cleanup(func() { _ = backend.Close() })
+	return backend, Config{
+		Log:      hs.LogConf{Level: "error", Outputs: []string{"stderr"}, Errors: []string{"stderr"}},
+		Contract: "0x00000000000000000000000000000000000c0de0",
+		ChainID:  testChainID,
+		Mnemonic: mnemonic,
+	}
+}
+
+func addresses(n int) []common.Address {
+	var list []common.Address
+	for i := 1; i <= n; i++ {
+		list = append(list, common.BigToAddress(big.NewInt(int64(0xca00+i))))
+	}
+	return list
+}
+
+func TestAddWhitelistAndAirdrop(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	if err := h.owner.CheckOwner(ctx, common.Address{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list := addresses(3)
+	if err := h.owner.AddWhitelist(ctx, list[:2], 2); err != nil {
+		t.Fatal(err)
+	}
+	for i, owner := range list {
+		want := uint8(2)
+		if i == 2 {
+			want = 0
+		}
+		q, err := h.owner.MintQuota(ctx, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Cap != want || q.Minted != 0 {
+			t.Errorf("mintQuota of %s = %+v, want cap %d", owner.Hex(), q, want)
+		}
+	}
+
+	// one transaction per address
+	h.owner.cfg.ChunkSize = 1
+	before := h.backend.Blockchain().CurrentBlock().NumberU64()
+	if err := h.owner.AddAirdrop(ctx, list, 5); err != nil {
+		t.Fatal(err)
+	}
+	if mined := h.backend.Blockchain().CurrentBlock().NumberU64() - before; mined != 3 {
+		t.Errorf("addAirdrop in %d transactions, want 3", mined)
+	}
+	for _, owner := range list {
+		q, err := h.owner.AirdropQuota(ctx, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Cap != 5 {
+			t.Errorf("airdropQuota of %s = %+v, want cap 5", owner.Hex(), q)
+		}
+	}
+}
+
+func TestPauseAndPhase(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	if err := h.owner.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := h.owner.Paused(ctx); !paused {
+		t.Fatal("not paused after Pause")
+	}
+	// already paused, nothing is sent
+	before := h.backend.Blockchain().CurrentBlock().NumberU64()
+	if err := h.owner.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if h.backend.Blockchain().CurrentBlock().NumberU64() != before {
+		t.Error("Pause sent a transaction while paused")
+	}
+	if err := h.owner.Unpause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if paused, _ := h.owner.Paused(ctx); paused {
+		t.Fatal("paused after Unpause")
+	}
+
+	for _, phase := range []int8{1, 2, 0} {
+		if err := h.owner.SetPhase(ctx, phase); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := h.owner.Phase(ctx); got != phase {
+			t.Fatalf("phase = %d, want %d", got, phase)
+		}
+	}
+}
+
+func TestAdminReverts(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	if err := h.other.CheckOwner(ctx, common.Address{}); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("CheckOwner of another account: err = %v, want ErrNotOwner", err)
+	}
+	for name, call := range map[string]func() error{
+		"addWhitelist": func() error { return h.other.AddWhitelist(ctx, addresses(1), 1) },
+		"addAirdrop":   func() error { return h.other.AddAirdrop(ctx, addresses(1), 1) },
+		"pause":        func() error { return h.other.Pause(ctx) },
+		"setPhase":     func() error { return h.other.SetPhase(ctx, 1) },
+	} {
+		if err := call(); !errors.Is(err, ErrReverted) {
+			t.Errorf("%s from another account: err = %v, want ErrReverted", name, err)
+		}
+	}
+	if q, _ := h.owner.MintQuota(ctx, addresses(1)[0]); q.Cap != 0 {
+		t.Errorf("reverted addWhitelist set cap %d", q.Cap)
+	}
+	if phase, _ := h.owner.Phase(ctx); phase != 0 {
+		t.Errorf("reverted setPhase set phase %d", phase)
+	}
+
+	// not paused, so unpause reverts; Unpause itself checks first and skips
+	if err := h.owner.transact(ctx, "unpause"); !errors.Is(err, ErrReverted) {
+		t.Errorf("unpause while not paused: err = %v, want ErrReverted", err)
+	}
+	if err := h.owner.transact(ctx, "initialize"); !errors.Is(err, ErrReverted) {
+		t.Errorf("second initialize: err = %v, want ErrReverted", err)
+	}
+
+	// a dry run reports the reason
+	h.owner.SetDryRun(true)
+	if err := h.owner.transact(ctx, "unpause"); err != nil {
+		t.Fatal(err)
+	}
+	reports := h.owner.DryRunReports()
+	if len(reports) != 1 || reports[0].OK {
+		t.Fatalf("dry run reports = %+v, want one revert", reports)
+	}
+	if reports[0].Revert != cyber.ReasonNotPaused {
+		t.Errorf("dry run revert = %q, want %q", reports[0].Revert, cyber.ReasonNotPaused)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	h := newHarness(t)
+	info, err := h.other.Info(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := h.owner.signerAccount()
+	if info.Owner != owner.Address() || !info.ERC721 || !info.ERC721Enumerable {
+		t.Errorf("info = %+v, want owner %s and ERC-721 enumerable", info, owner.Address().Hex())
+	}
+	if info.Name == "" || info.Capacity.Sign() <= 0 || info.TotalSupply.Sign() != 0 {
+		t.Errorf("info = %+v, want the collection constants and no token yet", info)
+	}
+}
@@ -0,0 +1,130 @@
+package node
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+)
+
+// PendingNonceReader reads the next nonce of an account, counting the pool.
+type PendingNonceReader interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager assigns nonces for one account, so that back-to-back and
+// concurrent sends never reuse a nonce still pending in the pool.
+//
+// Every call to Next reserves a nonce, which must be Release-d if the node did
+// not accept the transaction. Released nonces below the highest assigned one are
+// gaps, and are handed out again first.
+type NonceManager struct {
+	account common.Address
+	backend PendingNonceReader
+	sugar   *zap.SugaredLogger
+
+	mu       sync.Mutex
+	synced   bool
+	next     uint64
+	reserved map[uint64]bool // assigned and not released, by nonce
+}
+
+func NewNonceManager(account common.Address, backend PendingNonceReader, sugar *zap.SugaredLogger) *NonceManager {
+	return &NonceManager{
+		account:  account,
+		backend:  backend,
+		sugar:    sugar,
+		reserved: make(map[uint64]bool),
+	}
+}
+
+// Next reserves the nonce for the next transaction.
+func (m *NonceManager) Next(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, err := m.backend.PendingNonceAt(ctx, m.account)
+	if err != nil {
+		return 0, err
+	}
+	if !m.synced || pending > m.next {
+		if m.synced {
+			// someone else sent from this account
+			m.sugar.Infof("nonce of %s moved to %d outside this process", m.account, pending)
+		}
+		m.next = pending
+		m.synced = true
+	}
+	// everything below pending is in the pool or mined already
+	for nonce := range m.reserved {
+		if nonce < pending {
+			delete(m.reserved, nonce)
+		}
+	}
+	// fill the lowest gap first, a later transaction can't be mined before it
+	for nonce := pending; nonce < m.next; nonce++ {
+		if !m.reserved[nonce] {
+			m.sugar.Warnf("nonce gap of %s at %d, filling it", m.account, nonce)
+			m.reserved[nonce] = true
+			return nonce, nil
+		}
+	}
+	nonce := m.next
+	m.next++
+	m.reserved[nonce] = true
+	return nonce, nil
+}
+
+// Release gives back a nonce whose transaction was never accepted by the node.
+func (m *NonceManager) Release(nonce uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, nonce)
+	if nonce+1 == m.next {
+		m.next = nonce
+	}
+}
+
+// Resync drops all local state, the next nonce is read from the node again.
+// Use it after the node rejected a nonce as too low.
+func (m *NonceManager) Resync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.synced = false
+	m.reserved = make(map[uint64]bool)
+}
+
+// Gaps returns the nonces between the node's pending nonce and the highest
+// assigned one, which are not reserved by any transaction of this process.
+func (m *NonceManager) Gaps(ctx context.Context) ([]uint64, error) {
+	pending, err := m.backend.PendingNonceAt(ctx, m.account)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var gaps []uint64
+	for nonce := pending; nonce < m.next; nonce++ {
+		if !m.reserved[nonce] {
+			gaps = append(gaps, nonce)
+		}
+	}
+	return gaps, nil
+}
+
+// isNonceError tells whether the node rejected the transaction for its nonce,
+// meaning the local view of the account is stale. "already known" is not one:
+// the very transaction is in the pool already, sending it again with another
+// nonce would run the call twice.
+func isNonceError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
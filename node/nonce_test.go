@@ -0,0 +1,129 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
+	"reflect"
+	"testing"
+)
+
+// pendingNonce is the pending nonce of the node.
+type pendingNonce uint64
+
+func (p *pendingNonce) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return uint64(*p), nil
+}
+
+func TestNonceManager(t *testing.T) {
+	ctx := context.Background()
+	pending := pendingNonce(5)
+	m := NewNonceManager(common.HexToAddress("0x01"), &pending, zap.NewNop().Sugar())
+	next := func(want uint64) {
+		t.Helper()
+		nonce, err := m.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nonce != want {
+			t.Fatalf("Next = %d, want %d", nonce, want)
+		}
+	}
+	gaps := func(want ...uint64) {
+		t.Helper()
+		got, err := m.Gaps(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Gaps = %v, want %v", got, want)
+		}
+	}
+
+	// back-to-back sends don't wait for the pool
+	next(5)
+	next(6)
+	next(7)
+	gaps()
+
+	// the last nonce given back is handed out again
+	m.Release(7)
+	next(7)
+
+	// a nonce given back below the last one is a gap, filled first
+	m.Release(5)
+	gaps(5)
+	next(5)
+	gaps()
+	next(8)
+
+	// another process sent from the account
+	pending = 12
+	next(12)
+
+	// the node dropped the pool, the nonces below are gaps to fill
+	pending = 3
+	next(3)
+	gaps(4, 5, 6, 7, 8, 9, 10, 11)
+
+	// a Resync forgets the reservations and starts over from the node
+	m.Resync()
+	next(3)
+	next(4)
+	gaps()
+}
+
+func TestIsNonceError(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"nonce too low":                              true,
+		"replacement transaction underpriced":        true,
+		"already known":                              false,
+		"insufficient funds for gas * price + value": false,
+	} {
+		if got := isNonceError(errors.New(msg)); got != want {
+			t.Errorf("isNonceError(%q) = %v, want %v", msg, got, want)
+		}
+	}
+	if isNonceError(nil) {
+		t.Error("isNonceError(nil) = true")
+	}
+}
+
+// rejectFirst fails the first send with err, as the node would.
+type rejectFirst struct {
+	autoMine
+	err   error
+	sends int
+}
+
+func (b *rejectFirst) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if b.sends++; b.sends == 1 {
+		return b.err
+	}
+	return b.autoMine.SendTransaction(ctx, tx)
+}
+
+func TestTransactNonceRetry(t *testing.T) {
+	for msg, want := range map[string]int{
+		"nonce too low":                       2,
+		"replacement transaction underpriced": 2,
+		"already known":                       1,
+	} {
+		sim, cfg := newBare(t)
+		backend := &rejectFirst{autoMine: autoMine{sim}, err: errors.New(msg)}
+		n := NewWithBackend(cfg, backend)
+		if err := n.Init(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		err := n.transact(context.Background(), "pause")
+		if (want == 2) != (err == nil) || backend.sends != want {
+			t.Errorf("%s: err = %v after %d sends, want %d sends", msg, err, backend.sends, want)
+		}
+		account, _ := n.signerAccount()
+		if nonce, _ := sim.NonceAt(context.Background(), account.Address(), nil); nonce != uint64(want-1) {
+			t.Errorf("%s: %d transactions mined, want %d", msg, nonce, want-1)
+		}
+	}
+}
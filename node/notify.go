@@ -0,0 +1,374 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/xyths/hs/broadcast"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Kinds of the notified events.
+const (
+	EventTxSent               = "txSent"
+	EventTxConfirmed          = "txConfirmed"
+	EventTxReverted           = "txReverted"
+	EventPaused               = "paused"
+	EventUnpaused             = "unpaused"
+	EventOwnershipTransferred = "ownershipTransferred"
+	EventTransferBurst        = "transferBurst"
+	EventSchedule             = "schedule"
+)
+
+// Channel types.
+const (
+	ChannelDingTalk = "dingtalk"
+	ChannelSlack    = "slack"
+	ChannelWebhook  = "webhook"
+)
+
+// DefaultTransferBurst is the transfers in one block notified as a burst.
+const DefaultTransferBurst = 50
+
+// DefaultTemplates are the messages of the events, a channel overrides them
+// in NotifyChannel.Templates. The templates are text/template over Event.
+var DefaultTemplates = map[string]string{
+	EventTxSent:               "[{{.Network}}] {{.Method}} sent from {{.Account.Hex}}, tx {{.Tx.Hex}}",
+	EventTxConfirmed:          "[{{.Network}}] {{.Method}} confirmed in block {{.Block}}, tx {{.Tx.Hex}}",
+	EventTxReverted:           "[{{.Network}}] {{.Method}} REVERTED in block {{.Block}}, tx {{.Tx.Hex}}",
+	EventPaused:               "[{{.Network}}] contract {{.Contract.Hex}} paused by {{.Account.Hex}} in block {{.Block}}",
+	EventUnpaused:             "[{{.Network}}] contract {{.Contract.Hex}} unpaused by {{.Account.Hex}} in block {{.Block}}",
+	EventOwnershipTransferred: "[{{.Network}}] ownership of {{.Contract.Hex}} transferred from {{.Previous.Hex}} to {{.Owner.Hex}} in block {{.Block}}",
+	EventTransferBurst:        "[{{.Network}}] {{.Count}} transfers of {{.Contract.Hex}} in block {{.Block}}",
+	EventSchedule:             "[{{.Network}}] scheduled {{.Action}} {{.Status}} after {{.Attempts}} attempts{{if .Note}}: {{.Note}}{{end}}",
+}
+
+// Event is something to notify about.
+type Event struct {
+	Kind     string         `json:"kind"`
+	Time     time.Time      `json:"time"`
+	Network  string         `json:"network,omitempty"`
+	Contract common.Address `json:"contract"`
+	// Method is the contract method of the tx events.
+	Method string      `json:"method,omitempty"`
+	Tx     common.Hash `json:"tx,omitempty"`
+	Block  uint64      `json:"block,omitempty"`
+	// Account sent the transaction, or paused or unpaused the contract.
+	Account common.Address `json:"account,omitempty"`
+	// Previous and Owner are the owners of an ownership transfer.
+	Previous common.Address `json:"previous,omitempty"`
+	Owner    common.Address `json:"owner,omitempty"`
+	// Count is the transfers of a burst.
+	Count int `json:"count,omitempty"`
+	// Action, Status, Attempts and Note are the outcome of a scheduled action,
+	// Note is why it was skipped or failed.
+	Action   string `json:"action,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// NotifyConfig is where and what to notify.
+type NotifyConfig struct {
+	Channels []NotifyChannel `json:"channels"`
+	// TransferBurst is the transfers in one block notified as a burst, default
+	// DefaultTransferBurst.
+	TransferBurst int `json:"transferBurst"`
+}
+
+// NotifyChannel is a destination of notifications.
+type NotifyChannel struct {
+	// Type is dingtalk, slack (or a Slack-compatible webhook, e.g. Mattermost)
+	// or webhook, which gets the Event as json with its message.
+	Type string `json:"type"`
+	// URL is the webhook, for DingTalk with its access_token.
+	URL string `json:"url"`
+	// Secret signs the DingTalk messages, if the robot requires it.
+	Secret string `json:"secret"`
+	// Events are the kinds sent to the channel, all if empty.
+	Events []string `json:"events"`
+	// Templates override DefaultTemplates by event kind.
+	Templates map[string]string `json:"templates"`
+}
+
+// validate checks the channel, field names the channel in the problems.
+func (c *NotifyChannel) validate(field string) []string {
+	var problems []string
+	switch c.Type {
+	case ChannelDingTalk, ChannelSlack, ChannelWebhook:
+	default:
+		problems = append(problems, fmt.Sprintf("%s.type %q unknown, use dingtalk, slack or webhook", field, c.Type))
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("%s.url %q is not a http(s) url", field, c.URL))
+	} else if c.Type == ChannelDingTalk && u.RawQuery == "" {
+		problems = append(problems, fmt.Sprintf("%s.url has no access_token", field))
+	}
+	for _, kind := range c.Events {
+		if _, ok := DefaultTemplates[kind]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.events: %q unknown, use one of %s", field, kind, strings.Join(eventKinds(), ", ")))
+		}
+	}
+	for kind, text := range c.Templates {
+		if _, ok := DefaultTemplates[kind]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.templates: %q unknown, use one of %s", field, kind, strings.Join(eventKinds(), ", ")))
+		} else if _, err := template.New(kind).Parse(text); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.templates.%s: %s", field, kind, err))
+		}
+	}
+	return problems
+}
+
+func eventKinds() []string {
+	kinds := make([]string, 0, len(DefaultTemplates))
+	for kind := range DefaultTemplates {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Notifier sends events to the channels which want them.
+type Notifier struct {
+	channels []*channel
+	burst    int
+}
+
+type channel struct {
+	NotifyChannel
+	events    map[string]bool
+	templates map[string]*template.Template
+	dingtalk  broadcast.Broadcaster
+}
+
+// NewNotifier returns the Notifier of cfg.
+func NewNotifier(cfg NotifyConfig) (*Notifier, error) {
+	nt := &Notifier{burst: cfg.TransferBurst}
+	if nt.burst <= 0 {
+		nt.burst = DefaultTransferBurst
+	}
+	for i, c := range cfg.Channels {
+		if problems := c.validate(fmt.Sprintf("notify.channels[%d]", i)); len(problems) > 0 {
+			return nil, &ValidationError{Problems: problems}
+		}
+		ch := &channel{NotifyChannel: c, templates: make(map[string]*template.Template)}
+		if len(c.Events) > 0 {
+			ch.events = make(map[string]bool)
+			for _, kind := range c.Events {
+				ch.events[kind] = true
+			}
+		}
+		for kind, text := range DefaultTemplates {
+			if custom, ok := c.Templates[kind]; ok {
+				text = custom
+			}
+			ch.templates[kind] = template.Must(template.New(kind).Parse(text))
+		}
+		if c.Type == ChannelDingTalk {
+			ch.dingtalk = broadcast.NewDingTalk(broadcast.Config{Name: broadcast.NameDingTalk, BaseUrl: c.URL, Secret: c.Secret})
+		}
+		nt.channels = append(nt.channels, ch)
+	}
+	return nt, nil
+}
+
+// Notify sends e to every channel which wants it, at once, and returns the
+// errors of the channels which failed.
+func (nt *Notifier) Notify(ctx context.Context, e Event) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []string
+	)
+	for _, ch := range nt.channels {
+		if ch.events != nil && !ch.events[e.Kind] {
+			continue
+		}
+		wg.Add(1)
+		go func(ch *channel) {
+			defer wg.Done()
+			if err := ch.send(ctx, e); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s %s: %s", ch.Type, redactQuery(ch.URL), err))
+				mu.Unlock()
+			}
+		}(ch)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New("notify " + e.Kind + ": " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// notifyTimeout bounds the post to a channel.
+const notifyTimeout = 10 * time.Second
+
+func (ch *channel) send(ctx context.Context, e Event) error {
+	var msg bytes.Buffer
+	if err := ch.templates[e.Kind].Execute(&msg, e); err != nil {
+		return err
+	}
+	switch ch.Type {
+	case ChannelDingTalk:
+		return ch.sendDingTalk(ctx, msg.String())
+	case ChannelSlack:
+		return postJSON(ctx, ch.URL, map[string]string{"text": msg.String()})
+	default:
+		return postJSON(ctx, ch.URL, struct {
+			Event
+			Message string `json:"message"`
+		}{e, msg.String()})
+	}
+}
+
+// sendDingTalk sends through the hs robot, which takes no context and panics
+// on a failed request, hence the goroutine and the recover.
+func (ch *channel) sendDingTalk(ctx context.Context, msg string) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("dingtalk request failed: %v", r)
+			}
+		}()
+		done <- ch.dingtalk.SendText(msg)
+	}()
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// redactQuery drops the query of a webhook url for error messages, it holds
+// the access token.
+func redactQuery(raw string) string {
+	if i := strings.IndexByte(raw, '?'); i >= 0 {
+		return raw[:i]
+	}
+	return raw
+}
+
+// ContractEvents turns the logs of the contract into events: Paused,
+// Unpaused and OwnershipTransferred each, and the Transfers of a block when
+// there are at least Config.Notify.TransferBurst of them.
+func (nt *Notifier) ContractEvents(logs []ContractEvent) []Event {
+	var events []Event
+	transfers := make(map[uint64]int)
+	var blocks []uint64
+	for _, l := range logs {
+		e := Event{Block: l.Block, Tx: l.Tx}
+		switch l.Name {
+		case "Paused":
+			e.Kind, e.Account = EventPaused, l.Account
+		case "Unpaused":
+			e.Kind, e.Account = EventUnpaused, l.Account
+		case "OwnershipTransferred":
+			e.Kind, e.Previous, e.Owner = EventOwnershipTransferred, l.From, l.To
+		case "Transfer":
+			if transfers[l.Block] == 0 {
+				blocks = append(blocks, l.Block)
+			}
+			transfers[l.Block]++
+			continue
+		default:
+			continue
+		}
+		events = append(events, e)
+	}
+	for _, block := range blocks {
+		if count := transfers[block]; count >= nt.burst {
+			events = append(events, Event{Kind: EventTransferBurst, Block: block, Count: count})
+		}
+	}
+	return events
+}
+
+// SetNotifier sets where the transactions sent and the contract events are
+// notified, nil for nowhere. Init sets it from Config.Notify.
+func (n *Node) SetNotifier(nt *Notifier) {
+	n.notifier = nt
+}
+
+// notify fills in the contract and network of e and sends it. Failures are
+// logged only, a notification never fails the action it reports.
+func (n *Node) notify(ctx context.Context, e Event) {
+	if n.notifier == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Contract = n.contract
+	e.Network = n.cfg.Network
+	if err := n.notifier.Notify(ctx, e); err != nil {
+		n.Sugar.Warnf("%s", err)
+	}
+}
+
+// waitNotified is waitMined for the transaction of method, notifying it was
+// sent and then confirmed or reverted. Other wait errors, e.g. a timeout, say
+// nothing of the outcome and are not notified.
+func (n *Node) waitNotified(ctx context.Context, method string, tx *types.Transaction) (*types.Receipt, error) {
+	n.notify(ctx, n.txEvent(EventTxSent, method, tx))
+	return n.waitMinedNotified(ctx, method, tx)
+}
+
+// waitMinedNotified waits for tx, already notified as sent, and notifies its
+// outcome.
+func (n *Node) waitMinedNotified(ctx context.Context, method string, tx *types.Transaction) (*types.Receipt, error) {
+	receipt, err := n.waitReceipt(ctx, tx)
+	if receipt != nil {
+		e := n.txEvent(EventTxConfirmed, method, tx)
+		e.Tx, e.Block = receipt.TxHash, receipt.BlockNumber.Uint64()
+		if errors.Is(err, ErrReverted) {
+			e.Kind = EventTxReverted
+		}
+		n.notify(ctx, e)
+	}
+	return receipt, err
+}
+
+func (n *Node) txEvent(kind, method string, tx *types.Transaction) Event {
+	e := Event{Kind: kind, Method: method, Tx: tx.Hash()}
+	if n.signer != nil {
+		e.Account = n.signer.Address()
+	}
+	return e
+}
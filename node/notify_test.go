@@ -0,0 +1,210 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hooks records the json posted to a test server, by path.
+type hooks struct {
+	mu    sync.Mutex
+	posts map[string][]map[string]interface{}
+}
+
+func newHooks(t *testing.T) (*hooks, string) {
+	h := &hooks{posts: make(map[string][]map[string]interface{})}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.mu.Lock()
+		h.posts[r.URL.Path] = append(h.posts[r.URL.Path], body)
+		h.mu.Unlock()
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return h, srv.URL
+}
+
+func (h *hooks) take(path string) []map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	posts := h.posts[path]
+	delete(h.posts, path)
+	return posts
+}
+
+func TestNotifyTx(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	hooks, url := newHooks(t)
+	nt, err := NewNotifier(NotifyConfig{Channels: []NotifyChannel{
+		{Type: ChannelWebhook, URL: url + "/webhook"},
+		{Type: ChannelSlack, URL: url + "/slack", Events: []string{EventTxReverted},
+			Templates: map[string]string{EventTxReverted: "{{.Method}} reverted"}},
+		{Type: ChannelDingTalk, URL: url + "/robot/send?access_token=t", Secret: "s", Events: []string{EventTxConfirmed}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.owner.SetNotifier(nt)
+	h.other.SetNotifier(nt)
+
+	if err = h.owner.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	posts := hooks.take("/webhook")
+	if len(posts) != 2 || posts[0]["kind"] != EventTxSent || posts[1]["kind"] != EventTxConfirmed {
+		t.Fatalf("webhook got %v, want txSent and txConfirmed", posts)
+	}
+	if contract, _ := posts[1]["contract"].(string); posts[1]["method"] != "pause" || !strings.EqualFold(contract, h.contract.Hex()) {
+		t.Errorf("confirmed = %v, want pause of %s", posts[1], h.contract.Hex())
+	}
+	if msg, _ := posts[1]["message"].(string); !strings.Contains(msg, "pause confirmed in block") {
+		t.Errorf("message = %q", msg)
+	}
+	ding := hooks.take("/robot/send")
+	if len(ding) != 1 {
+		t.Fatalf("dingtalk got %v, want txConfirmed only", ding)
+	}
+	if text, _ := ding[0]["text"].(map[string]interface{}); text == nil || !strings.Contains(text["content"].(string), "pause confirmed") {
+		t.Errorf("dingtalk got %v", ding[0])
+	}
+	if slack := hooks.take("/slack"); len(slack) != 0 {
+		t.Errorf("slack got %v, want nothing", slack)
+	}
+
+	// the other account is not the owner, unpause reverts
+	if err = h.other.transact(ctx, "unpause"); !errors.Is(err, ErrReverted) {
+		t.Fatalf("err = %v, want ErrReverted", err)
+	}
+	if slack := hooks.take("/slack"); len(slack) != 1 || slack[0]["text"] != "unpause reverted" {
+		t.Errorf("slack got %v, want the custom reverted text", slack)
+	}
+	if posts = hooks.take("/webhook"); len(posts) != 2 || posts[1]["kind"] != EventTxReverted {
+		t.Errorf("webhook got %v, want txSent and txReverted", posts)
+	}
+}
+
+func TestWatchNotify(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	hooks, url := newHooks(t)
+	nt, err := NewNotifier(NotifyConfig{Channels: []NotifyChannel{{Type: ChannelWebhook, URL: url + "/webhook"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.owner.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	h.other.SetNotifier(nt)
+
+	var got []ContractEvent
+	handle := func(from, to uint64, events []ContractEvent) {
+		got = append(got, events...)
+		for _, e := range nt.ContractEvents(events) {
+			h.other.notify(ctx, e)
+		}
+	}
+	next := uint64(1)
+	behind, err := h.other.watchRange(ctx, &next, 0, 1, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !behind || next != 2 {
+		t.Errorf("behind = %v, next = %d after block 1, want more to read from 2", behind, next)
+	}
+	for behind {
+		if behind, err = h.other.watchRange(ctx, &next, 0, 1, handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for _, e := range got {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, " ") != "OwnershipTransferred Paused" {
+		t.Errorf("events = %v, want OwnershipTransferred Paused", names)
+	}
+	posts := hooks.take("/webhook")
+	if len(posts) != 2 || posts[0]["kind"] != EventOwnershipTransferred || posts[1]["kind"] != EventPaused {
+		t.Fatalf("webhook got %v", posts)
+	}
+	owner, _ := h.owner.Owner(ctx)
+	if account, _ := posts[1]["account"].(string); !strings.EqualFold(account, owner.Hex()) {
+		t.Errorf("paused by %v, want %s", posts[1]["account"], owner.Hex())
+	}
+}
+
+func TestContractEventString(t *testing.T) {
+	a, b := common.HexToAddress("0x00000000000000000000000000000000000a11ce"), common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	for _, c := range []struct {
+		event ContractEvent
+		want  string
+	}{
+		{ContractEvent{Name: "Transfer", From: a, To: b, TokenId: big.NewInt(7)}, "Transfer of token 7 from " + a.Hex() + " to " + b.Hex()},
+		{ContractEvent{Name: "Paused", Account: a}, "Paused by " + a.Hex()},
+		{ContractEvent{Name: "OwnershipTransferred", From: a, To: b}, "OwnershipTransferred from " + a.Hex() + " to " + b.Hex()},
+	} {
+		if got := c.event.String(); got != c.want {
+			t.Errorf("%s event = %q, want %q", c.event.Name, got, c.want)
+		}
+	}
+}
+
+func TestTransferBurst(t *testing.T) {
+	nt, err := NewNotifier(NotifyConfig{TransferBurst: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs []ContractEvent
+	for i, block := range []uint64{7, 7, 8, 8, 8, 9, 8} {
+		logs = append(logs, ContractEvent{Name: "Transfer", Block: block, Index: uint(i)})
+	}
+	logs = append(logs, ContractEvent{Name: "Paused", Block: 9})
+	events := nt.ContractEvents(logs)
+	if len(events) != 2 || events[0].Kind != EventPaused || events[1].Kind != EventTransferBurst ||
+		events[1].Block != 8 || events[1].Count != 4 {
+		t.Errorf("events = %+v, want paused and a burst of 4 in block 8", events)
+	}
+}
+
+func TestNotifierConfig(t *testing.T) {
+	for name, c := range map[string]NotifyChannel{
+		"type":          {Type: "email", URL: "https://example.com/hook"},
+		"url":           {Type: ChannelWebhook, URL: "example.com/hook"},
+		"access token":  {Type: ChannelDingTalk, URL: "https://oapi.dingtalk.com/robot/send"},
+		"event":         {Type: ChannelSlack, URL: "https://hooks.slack.com/services/x", Events: []string{"minted"}},
+		"template":      {Type: ChannelSlack, URL: "https://hooks.slack.com/services/x", Templates: map[string]string{EventPaused: "{{.Block"}},
+		"template kind": {Type: ChannelSlack, URL: "https://hooks.slack.com/services/x", Templates: map[string]string{"minted": "x"}},
+	} {
+		var ve *ValidationError
+		if _, err := NewNotifier(NotifyConfig{Channels: []NotifyChannel{c}}); !errors.As(err, &ve) {
+			t.Errorf("bad %s: err = %v, want a ValidationError", name, err)
+		}
+	}
+
+	// a dead endpoint fails the notification, not the caller
+	_, url := newHooks(t)
+	nt, err := NewNotifier(NotifyConfig{Channels: []NotifyChannel{
+		{Type: ChannelDingTalk, URL: "http://127.0.0.1:1/robot/send?access_token=secret"},
+		{Type: ChannelWebhook, URL: url + "/webhook"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = nt.Notify(context.Background(), Event{Kind: EventPaused})
+	if err == nil || !strings.Contains(err.Error(), "dingtalk") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("err = %v, want the dingtalk failure without its token", err)
+	}
+}
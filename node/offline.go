@@ -0,0 +1,202 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+)
+
+// UnsignedTx is a fully prepared transaction, to be signed on an offline machine.
+type UnsignedTx struct {
+	Method               string          `json:"method,omitempty"`
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+func NewUnsignedTx(from common.Address, chainId *big.Int, tx *types.Transaction) *UnsignedTx {
+	u := &UnsignedTx{
+		From:    from,
+		To:      tx.To(),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainId),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		u.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		u.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		u.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+	return u
+}
+
+// Transaction returns the transaction to be signed.
+func (u *UnsignedTx) Transaction() (*types.Transaction, error) {
+	if u.ChainID == nil {
+		return nil, errors.New("chainId missing")
+	}
+	value := new(big.Int)
+	if u.Value != nil {
+		value = u.Value.ToInt()
+	}
+	if u.MaxFeePerGas != nil {
+		if u.MaxPriorityFeePerGas == nil {
+			return nil, errors.New("maxPriorityFeePerGas missing")
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   u.ChainID.ToInt(),
+			Nonce:     uint64(u.Nonce),
+			GasTipCap: u.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: u.MaxFeePerGas.ToInt(),
+			Gas:       uint64(u.Gas),
+			To:        u.To,
+			Value:     value,
+			Data:      u.Data,
+		}), nil
+	}
+	if u.GasPrice == nil {
+		return nil, errors.New("gasPrice missing")
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    uint64(u.Nonce),
+		GasPrice: u.GasPrice.ToInt(),
+		Gas:      uint64(u.Gas),
+		To:       u.To,
+		Value:    value,
+		Data:     u.Data,
+	}), nil
+}
+
+// Sign signs the transaction, signer must hold the from account.
+func (u *UnsignedTx) Sign(signer Signer) (*types.Transaction, error) {
+	if signer.Address() != u.From {
+		return nil, fmt.Errorf("transaction is from %s, but the key is for %s", u.From, signer.Address())
+	}
+	tx, err := u.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	return signer.SignTx(tx, u.ChainID.ToInt())
+}
+
+func ReadUnsignedTx(filename string) (*UnsignedTx, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	u := &UnsignedTx{}
+	if err = json.Unmarshal(b, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *UnsignedTx) Write(filename string) error {
+	b, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+// ReadSignedTx reads a raw signed transaction, hex encoded.
+func ReadSignedTx(filename string) (*types.Transaction, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hexutil.Decode(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// WriteSignedTx writes tx as hex encoded raw transaction.
+func WriteSignedTx(filename string, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(hexutil.Encode(raw)+"\n"), 0644)
+}
+
+// SetUnsignedOut makes admin transactions be written to filename unsigned, instead of sent.
+func (n *Node) SetUnsignedOut(filename string) {
+	n.unsignedOut = filename
+}
+
+func (n *Node) writeUnsigned(ctx context.Context, auth *bind.TransactOpts, method string, params ...interface{}) error {
+	chainId, err := n.chainID(ctx)
+	if err != nil {
+		n.Sugar.Errorf("Get chainId error: %s", err)
+		return err
+	}
+	auth.NoSend = true
+	auth.Signer = func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		return tx, nil
+	}
+	// chunked calls are written to numbered files, the nonce manager keeps
+	// their nonces consecutive
+	filename := n.unsignedOut
+	if n.unsignedCount > 0 {
+		ext := filepath.Ext(filename)
+		filename = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filename, ext), n.unsignedCount, ext)
+	}
+	tx, err := n.raw.Transact(auth, method, params...)
+	if err != nil {
+		n.nonces.Release(auth.Nonce.Uint64())
+		n.Sugar.Errorf("%s error: %s", method, err)
+		return err
+	}
+	u := NewUnsignedTx(auth.From, chainId, tx)
+	u.Method = method
+	if err = u.Write(filename); err != nil {
+		n.Sugar.Errorf("write unsigned tx error: %s", err)
+		return err
+	}
+	n.unsignedCount++
+	n.Sugar.Infof("unsigned %s written to %s, nonce %d", method, filename, tx.Nonce())
+	return nil
+}
+
+// Broadcast sends a signed transaction and waits until it is mined.
+func (n *Node) Broadcast(ctx context.Context, tx *types.Transaction) error {
+	chainId, err := n.chainID(ctx)
+	if err != nil {
+		n.Sugar.Errorf("Get chainId error: %s", err)
+		return err
+	}
+	if tx.Protected() && tx.ChainId().Cmp(chainId) != 0 {
+		return fmt.Errorf("transaction is for chain %s, rpc is on chain %s", tx.ChainId(), chainId)
+	}
+	if err = n.backend.SendTransaction(ctx, tx); err != nil {
+		n.Sugar.Errorf("SendTransaction error: %s", err)
+		return err
+	}
+	n.Sugar.Infof("broadcast tx %s", tx.Hash().String())
+	return n.waitMined(ctx, tx)
+}
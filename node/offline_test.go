@@ -0,0 +1,76 @@
+package node
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"path/filepath"
+	"testing"
+)
+
+func TestOfflineRoundTrip(t *testing.T) {
+	sim, cfg := newBare(t)
+	ctx := context.Background()
+	n := NewWithBackend(cfg, sim)
+	if err := n.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	unsigned := filepath.Join(dir, "pause.json")
+	n.SetUnsignedOut(unsigned)
+	if err := n.transact(ctx, "pause"); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := ReadUnsignedTx(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewMnemonicSigner(cfg.Mnemonic, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Method != "pause" || u.From != signer.Address() || u.To == nil || *u.To != common.HexToAddress(cfg.Contract) || u.ChainID.ToInt().Int64() != testChainID {
+		t.Fatalf("unsigned tx %+v", u)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, signer.Address()); nonce != 0 {
+		t.Fatalf("unsigned out sent the tx, pending nonce %d", nonce)
+	}
+
+	// only the from account may sign it
+	other, err := NewMnemonicSigner(cfg.Mnemonic, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.Sign(other); err == nil {
+		t.Error("signed by another account: want an error")
+	}
+	tx, err := u.Sign(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(dir, "pause.signed")
+	if err = WriteSignedTx(signed, tx); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSignedTx(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Hash() != tx.Hash() {
+		t.Fatalf("read back %s, want %s", read.Hash().Hex(), tx.Hash().Hex())
+	}
+
+	// broadcasting needs no key
+	broadcaster := cfg
+	broadcaster.Mnemonic = ""
+	b := NewWithBackend(broadcaster, autoMine{sim})
+	if err = b.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Broadcast(ctx, read); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil || r.Status != 1 {
+		t.Fatalf("receipt %v, %v, want mined", r, err)
+	}
+}
@@ -0,0 +1,146 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+)
+
+// DesiredQuota is the cap an address should have.
+type DesiredQuota struct {
+	Address common.Address `json:"address"`
+	Cap     uint8          `json:"cap"`
+}
+
+// QuotaChange is an address whose cap differs from the desired one.
+type QuotaChange struct {
+	Address common.Address `json:"address"`
+	Minted  uint8          `json:"minted"`
+	Cap     uint8          `json:"cap"`
+	Want    uint8          `json:"want"`
+}
+
+// QuotaGroup is the changes set with one call per chunk, as addWhitelist and
+// addAirdrop take one amount for all their addresses.
+type QuotaGroup struct {
+	Amount  uint8         `json:"amount"`
+	Changes []QuotaChange `json:"changes"`
+}
+
+// QuotaPlan is what it takes to bring the on-chain quotas to the desired state.
+type QuotaPlan struct {
+	Kind      string       `json:"kind"`
+	Block     *big.Int     `json:"block"`
+	Unchanged int          `json:"unchanged"`
+	Groups    []QuotaGroup `json:"groups"`
+	// Conflicts want a cap below what the address has minted already, they are
+	// left out of the plan.
+	Conflicts []QuotaChange `json:"conflicts,omitempty"`
+}
+
+// Empty tells whether the plan has nothing to send.
+func (p *QuotaPlan) Empty() bool {
+	return len(p.Groups) == 0
+}
+
+// Equal tells whether p makes the same calls as o, e.g. a saved plan is still current.
+func (p *QuotaPlan) Equal(o *QuotaPlan) bool {
+	if p.Kind != o.Kind || len(p.Groups) != len(o.Groups) {
+		return false
+	}
+	for i, g := range p.Groups {
+		if g.Amount != o.Groups[i].Amount || len(g.Changes) != len(o.Groups[i].Changes) {
+			return false
+		}
+		for j, c := range g.Changes {
+			if c.Address != o.Groups[i].Changes[j].Address {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PlanQuota diffs the desired caps of kind against the chain at block, the
+// latest block if nil, and groups the needed changes by amount.
+func (n *Node) PlanQuota(ctx context.Context, kind string, desired []DesiredQuota, block *big.Int) (*QuotaPlan, error) {
+	quota := n.nft.MintQuota
+	switch kind {
+	case QuotaWhitelist:
+	case QuotaAirdrop:
+		quota = n.nft.AirdropQuota
+	default:
+		return nil, fmt.Errorf("unknown quota kind %s, use %s or %s", kind, QuotaWhitelist, QuotaAirdrop)
+	}
+	block, err := n.PinBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	plan := &QuotaPlan{Kind: kind, Block: block}
+	groups := make(map[uint8]*QuotaGroup)
+	for _, d := range desired {
+		q, err := quota(opts, d.Address)
+		if err != nil {
+			n.Sugar.Errorf("read %s quota of %s error: %s", kind, d.Address.Hex(), err)
+			return nil, err
+		}
+		c := QuotaChange{Address: d.Address, Minted: q.Minted, Cap: q.Cap, Want: d.Cap}
+		switch {
+		case q.Cap == d.Cap:
+			plan.Unchanged++
+		case d.Cap < q.Minted:
+			plan.Conflicts = append(plan.Conflicts, c)
+		default:
+			g, ok := groups[d.Cap]
+			if !ok {
+				g = &QuotaGroup{Amount: d.Cap}
+				groups[d.Cap] = g
+			}
+			g.Changes = append(g.Changes, c)
+		}
+	}
+	for _, g := range groups {
+		plan.Groups = append(plan.Groups, *g)
+	}
+	sort.Slice(plan.Groups, func(i, j int) bool {
+		return plan.Groups[i].Amount < plan.Groups[j].Amount
+	})
+	return plan, nil
+}
+
+// ApplyQuota sends the calls of the plan, one group after the other, each
+// chunked by Config.ChunkSize.
+func (n *Node) ApplyQuota(ctx context.Context, plan *QuotaPlan) error {
+	add := n.AddWhitelist
+	switch plan.Kind {
+	case QuotaWhitelist:
+	case QuotaAirdrop:
+		add = n.AddAirdrop
+	default:
+		return fmt.Errorf("can't apply a plan of kind %s", plan.Kind)
+	}
+	for _, g := range plan.Groups {
+		owners := make([]common.Address, 0, len(g.Changes))
+		for _, c := range g.Changes {
+			owners = append(owners, c.Address)
+		}
+		n.Sugar.Infof("apply %s amount %d to %d addresses", plan.Kind, g.Amount, len(owners))
+		if err := add(ctx, owners, g.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Chunks is the number of transactions a call with count addresses takes.
+func (n *Node) Chunks(count int) int {
+	size := n.cfg.ChunkSize
+	if size <= 0 || count <= size {
+		return 1
+	}
+	return (count + size - 1) / size
+}
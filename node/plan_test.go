@@ -0,0 +1,105 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanQuota(t *testing.T) {
+	n, c := newStateNode(t)
+	ctx := context.Background()
+	a := addresses(6)
+	// minted, cap on chain
+	c.whitelist[a[0]] = [2]uint8{0, 2} // already as wanted
+	c.whitelist[a[1]] = [2]uint8{1, 1} // raised to 3
+	c.whitelist[a[3]] = [2]uint8{2, 3} // lowered below its mints
+	c.whitelist[a[4]] = [2]uint8{1, 3} // lowered to 1, what it minted
+	desired := []DesiredQuota{
+		{a[0], 2}, {a[1], 3}, {a[2], 1}, {a[3], 1}, {a[4], 1}, {a[5], 3},
+	}
+	plan, err := n.PlanQuota(ctx, QuotaWhitelist, desired, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Kind != QuotaWhitelist || plan.Block == nil || plan.Unchanged != 1 || plan.Empty() {
+		t.Fatalf("plan %+v", plan)
+	}
+	if len(plan.Conflicts) != 1 || plan.Conflicts[0] != (QuotaChange{a[3], 2, 3, 1}) {
+		t.Errorf("conflicts %+v, want %s minted 2 wanting 1", plan.Conflicts, a[3].Hex())
+	}
+	// grouped by amount, ascending, in the order of the desired list
+	want := []QuotaGroup{
+		{Amount: 1, Changes: []QuotaChange{{a[2], 0, 0, 1}, {a[4], 1, 3, 1}}},
+		{Amount: 3, Changes: []QuotaChange{{a[1], 1, 1, 3}, {a[5], 0, 0, 3}}},
+	}
+	if len(plan.Groups) != len(want) {
+		t.Fatalf("groups %+v, want %+v", plan.Groups, want)
+	}
+	for i, g := range want {
+		got := plan.Groups[i]
+		if got.Amount != g.Amount || len(got.Changes) != len(g.Changes) {
+			t.Fatalf("group %d %+v, want %+v", i, got, g)
+		}
+		for j := range g.Changes {
+			if got.Changes[j] != g.Changes[j] {
+				t.Errorf("group %d change %d %+v, want %+v", i, j, got.Changes[j], g.Changes[j])
+			}
+		}
+	}
+
+	// nothing to do once the chain matches
+	for _, g := range plan.Groups {
+		for _, ch := range g.Changes {
+			c.whitelist[ch.Address] = [2]uint8{ch.Minted, ch.Want}
+		}
+	}
+	if done, err := n.PlanQuota(ctx, QuotaWhitelist, desired, nil); err != nil || !done.Empty() || done.Unchanged != 5 {
+		t.Errorf("plan after apply %+v, %v, want empty", done, err)
+	}
+
+	if _, err = n.PlanQuota(ctx, "reserve", desired, nil); err == nil {
+		t.Error("unknown kind: want an error")
+	}
+}
+
+func TestQuotaPlanEqual(t *testing.T) {
+	n, c := newStateNode(t)
+	ctx := context.Background()
+	a := addresses(3)
+	desired := []DesiredQuota{{a[0], 1}, {a[1], 2}, {a[2], 2}}
+	plan, err := n.PlanQuota(ctx, QuotaWhitelist, desired, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a plan saved to a file is still current while the chain doesn't move
+	b, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &QuotaPlan{}
+	if err = json.Unmarshal(b, saved); err != nil {
+		t.Fatal(err)
+	}
+	current, err := n.PlanQuota(ctx, QuotaWhitelist, desired, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Equal(saved) {
+		t.Errorf("saved plan %+v differs from %+v", saved, current)
+	}
+
+	// someone set a cap in between: the plan makes other calls
+	c.whitelist[a[1]] = [2]uint8{0, 2}
+	if current, err = n.PlanQuota(ctx, QuotaWhitelist, desired, nil); err != nil {
+		t.Fatal(err)
+	}
+	if current.Equal(saved) {
+		t.Error("plan after a change on chain equals the saved one")
+	}
+	// the same changes for the other list are other calls
+	if airdrop, err := n.PlanQuota(ctx, QuotaAirdrop, desired, nil); err != nil || airdrop.Equal(current) {
+		t.Errorf("airdrop plan %+v, %v, want it to differ from the whitelist plan", airdrop, err)
+	}
+}
@@ -0,0 +1,140 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+)
+
+// defaultBumpPercent is above the 10% price bump geth requires for a replacement.
+const defaultBumpPercent = 12
+
+const defaultMaxBumps = 3
+
+// SpeedUp resends the pending transaction hash with the same nonce and higher fees.
+func (n *Node) SpeedUp(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
+	return n.replacePending(ctx, hash, false)
+}
+
+// Cancel replaces the pending transaction hash with a 0-value self transfer,
+// with the same nonce and higher fees.
+func (n *Node) Cancel(ctx context.Context, hash common.Hash) (*types.Transaction, error) {
+	return n.replacePending(ctx, hash, true)
+}
+
+func (n *Node) replacePending(ctx context.Context, hash common.Hash, cancel bool) (*types.Transaction, error) {
+	tx, isPending, err := n.backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		n.Sugar.Errorf("get tx %s error: %s", hash.String(), err)
+		return nil, err
+	}
+	if !isPending {
+		return nil, fmt.Errorf("tx %s is already mined", hash.String())
+	}
+	replacement, err := n.replace(ctx, tx, cancel)
+	if err != nil {
+		n.Sugar.Errorf("replace tx %s error: %s", hash.String(), err)
+		return nil, err
+	}
+	n.Sugar.Infof("tx %s replaced by %s, nonce %d", hash.String(), replacement.Hash().String(), tx.Nonce())
+	return replacement, nil
+}
+
+// replace signs and sends a copy of tx with bumped fees, or a self transfer if cancel.
+func (n *Node) replace(ctx context.Context, tx *types.Transaction, cancel bool) (*types.Transaction, error) {
+	signer, err := n.signerAccount()
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := n.chainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainId), tx)
+	if err != nil {
+		return nil, err
+	}
+	if from != signer.Address() {
+		return nil, fmt.Errorf("tx is from %s, not from the signer %s", from, signer.Address())
+	}
+
+	to, value, data, gas := tx.To(), tx.Value(), tx.Data(), tx.Gas()
+	if cancel {
+		to, value, data, gas = &from, new(big.Int), nil, 21000
+	}
+	var unsigned *types.Transaction
+	switch tx.Type() {
+	case types.DynamicFeeTxType:
+		tip, err := n.backend.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		head, err := n.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if head.BaseFee == nil {
+			return nil, errors.New("dynamic fee tx on a chain without base fee")
+		}
+		tip = maxBig(n.bump(tx.GasTipCap()), tip)
+		feeCap := maxBig(n.bump(tx.GasFeeCap()), new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))))
+		unsigned = types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainId,
+			Nonce:     tx.Nonce(),
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	default:
+		gasPrice, err := n.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		unsigned = types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: maxBig(n.bump(tx.GasPrice()), gasPrice),
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		})
+	}
+	signed, err := signer.SignTx(unsigned, chainId)
+	if err != nil {
+		return nil, err
+	}
+	if err = n.backend.SendTransaction(ctx, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// bump raises fee by the configured percent.
+func (n *Node) bump(fee *big.Int) *big.Int {
+	percent := n.cfg.BumpPercent
+	if percent <= 0 {
+		percent = defaultBumpPercent
+	}
+	bumped := new(big.Int).Mul(fee, big.NewInt(int64(100+percent)))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func (n *Node) maxBumps() int {
+	if n.cfg.MaxBumps <= 0 {
+		return defaultMaxBumps
+	}
+	return n.cfg.MaxBumps
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,191 @@
+package node
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// pool keeps the transactions sent pending, as a node's pool does with a
+// replacement, which the simulated backend refuses for its used nonce.
+type pool struct {
+	*backends.SimulatedBackend
+	pending map[common.Hash]*types.Transaction
+}
+
+func (p *pool) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if tx, ok := p.pending[hash]; ok {
+		return tx, true, nil
+	}
+	return p.SimulatedBackend.TransactionByHash(ctx, hash)
+}
+
+func (p *pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	p.pending[tx.Hash()] = tx
+	return nil
+}
+
+func newPoolNode(t *testing.T) (*Node, *pool, Signer) {
+	t.Helper()
+	sim, cfg := newBare(t)
+	p := &pool{SimulatedBackend: sim, pending: make(map[common.Hash]*types.Transaction)}
+	n := NewWithBackend(cfg, p)
+	if err := n.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMnemonicSigner(cfg.Mnemonic, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, p, s
+}
+
+// sendPending signs unsigned and puts it in the pool.
+func (p *pool) sendPending(t *testing.T, s Signer, unsigned *types.Transaction) *types.Transaction {
+	t.Helper()
+	tx, err := s.SignTx(unsigned, big.NewInt(testChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.pending[tx.Hash()] = tx
+	return tx
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+func TestSpeedUpLegacy(t *testing.T) {
+	n, p, s := newPoolNode(t)
+	ctx := context.Background()
+	contract := common.HexToAddress(n.cfg.Contract)
+	data := common.FromHex("0x8456cb59")
+	tx := p.sendPending(t, s, types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: gwei(100), Gas: 50000, To: &contract, Data: data}))
+
+	sped, err := n.SpeedUp(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sped.Nonce() != 0 || *sped.To() != contract || sped.Gas() != 50000 || string(sped.Data()) != string(data) {
+		t.Errorf("speedup changed the call: %+v", sped)
+	}
+	if want := gwei(112); sped.GasPrice().Cmp(want) != 0 {
+		t.Errorf("gas price %s, want %s, 12%% more", sped.GasPrice(), want)
+	}
+	if p.pending[sped.Hash()] == nil {
+		t.Error("speedup not sent")
+	}
+
+	// a price below the network's is raised to it
+	cheap := p.sendPending(t, s, types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(1), Gas: 50000, To: &contract, Data: data}))
+	suggested, err := p.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sped, err = n.SpeedUp(ctx, cheap.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if sped.GasPrice().Cmp(suggested) != 0 {
+		t.Errorf("gas price %s, want the suggested %s", sped.GasPrice(), suggested)
+	}
+}
+
+func TestCancelLegacy(t *testing.T) {
+	n, p, s := newPoolNode(t)
+	contract := common.HexToAddress(n.cfg.Contract)
+	tx := p.sendPending(t, s, types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: gwei(100), Gas: 50000, To: &contract, Value: big.NewInt(5), Data: common.FromHex("0x8456cb59")}))
+
+	cancel, err := n.Cancel(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel.Nonce() != 0 || *cancel.To() != s.Address() || cancel.Value().Sign() != 0 || len(cancel.Data()) != 0 || cancel.Gas() != 21000 {
+		t.Errorf("cancel is not a 0-value self transfer: to %s, value %s, data %x, gas %d", cancel.To().Hex(), cancel.Value(), cancel.Data(), cancel.Gas())
+	}
+	if want := gwei(112); cancel.GasPrice().Cmp(want) != 0 {
+		t.Errorf("gas price %s, want %s", cancel.GasPrice(), want)
+	}
+}
+
+func TestReplaceDynamicFee(t *testing.T) {
+	n, p, s := newPoolNode(t)
+	ctx := context.Background()
+	contract := common.HexToAddress(n.cfg.Contract)
+	head, err := p.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := p.sendPending(t, s, types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(testChainID), Nonce: 0, GasTipCap: gwei(2), GasFeeCap: gwei(100), Gas: 50000, To: &contract}))
+
+	sped, err := n.SpeedUp(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sped.Type() != types.DynamicFeeTxType {
+		t.Fatalf("type %d, want a dynamic fee tx", sped.Type())
+	}
+	if want := new(big.Int).Div(gwei(224), big.NewInt(100)); sped.GasTipCap().Cmp(want) != 0 {
+		t.Errorf("tip %s, want %s", sped.GasTipCap(), want)
+	}
+	if want := gwei(112); sped.GasFeeCap().Cmp(want) != 0 {
+		t.Errorf("fee cap %s, want %s", sped.GasFeeCap(), want)
+	}
+
+	// fees below the network's are raised to the suggested tip and twice the base fee
+	cheap := p.sendPending(t, s, types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(testChainID), Nonce: 0, GasTipCap: new(big.Int), GasFeeCap: big.NewInt(1), Gas: 50000, To: &contract}))
+	tip, err := p.SuggestGasTipCap(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel, err := n.Cancel(ctx, cheap.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancel.GasTipCap().Cmp(tip) != 0 {
+		t.Errorf("tip %s, want the suggested %s", cancel.GasTipCap(), tip)
+	}
+	if want := new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))); cancel.GasFeeCap().Cmp(want) != 0 {
+		t.Errorf("fee cap %s, want %s", cancel.GasFeeCap(), want)
+	}
+	if *cancel.To() != s.Address() || cancel.Gas() != 21000 {
+		t.Errorf("cancel to %s gas %d, want a self transfer", cancel.To().Hex(), cancel.Gas())
+	}
+}
+
+func TestReplaceRefused(t *testing.T) {
+	n, p, s := newPoolNode(t)
+	ctx := context.Background()
+	to := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+
+	// mined already: the nonce is used, there is nothing to replace
+	mined, err := s.SignTx(types.NewTransaction(0, to, big.NewInt(1), 21000, gwei(10), nil), big.NewInt(testChainID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.SimulatedBackend.SendTransaction(ctx, mined); err != nil {
+		t.Fatal(err)
+	}
+	p.Commit()
+	for _, replace := range []func(context.Context, common.Hash) (*types.Transaction, error){n.SpeedUp, n.Cancel} {
+		if _, err = replace(ctx, mined.Hash()); err == nil || !strings.Contains(err.Error(), "already mined") {
+			t.Errorf("mined tx: err = %v, want already mined", err)
+		}
+	}
+
+	// someone else's transaction can't be replaced with our key
+	other, err := NewMnemonicSigner(n.cfg.Mnemonic, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs := p.sendPending(t, other, types.NewTransaction(0, to, big.NewInt(1), 21000, gwei(10), nil))
+	if _, err = n.SpeedUp(ctx, theirs.Hash()); err == nil {
+		t.Error("tx of another account: want an error")
+	}
+	if len(p.pending) != 1 {
+		t.Errorf("%d txs sent, want none", len(p.pending)-1)
+	}
+}
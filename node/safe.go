@@ -0,0 +1,251 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// MultiSendCallOnly is the canonical MultiSendCallOnly v1.3.0 deployment, used
+// by the Safe to execute a batch of calls in one SafeTx.
+var MultiSendCallOnly = common.HexToAddress("0x40A2aCCbd92BCA938b02010E17A5b8929b49130D")
+
+var (
+	safeDomainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	safeTxTypeHash     = crypto.Keccak256Hash([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))
+
+	carABI, _       = abi.JSON(strings.NewReader(cyber.CarABI))
+	multiSendABI, _ = abi.JSON(strings.NewReader(`[{"inputs":[{"internalType":"bytes","name":"transactions","type":"bytes"}],"name":"multiSend","outputs":[],"stateMutability":"payable","type":"function"}]`))
+	safeNonceABI, _ = abi.JSON(strings.NewReader(`[{"inputs":[],"name":"nonce","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`))
+)
+
+const (
+	SafeOperationCall         uint8 = 0
+	SafeOperationDelegateCall uint8 = 1
+)
+
+// SafeCall is one contract call in a Safe batch.
+type SafeCall struct {
+	Method string
+	To     common.Address
+	Value  *big.Int
+	Data   []byte
+}
+
+// SafeBatch collects admin calls for a Safe multisig to execute, instead of
+// sending them from an EOA.
+type SafeBatch struct {
+	Safe    common.Address
+	ChainID *big.Int
+	Nonce   *big.Int
+	Calls   []SafeCall
+}
+
+// Add encodes the CyberCar method call from cyber.CarABI and appends it to the batch.
+func (b *SafeBatch) Add(contract common.Address, method string, params ...interface{}) error {
+	data, err := carABI.Pack(method, params...)
+	if err != nil {
+		return err
+	}
+	b.Calls = append(b.Calls, SafeCall{Method: method, To: contract, Value: new(big.Int), Data: data})
+	return nil
+}
+
+// SafeTx is the transaction the Safe owners confirm.
+type SafeTx struct {
+	To             common.Address `json:"to"`
+	Value          *big.Int       `json:"value"`
+	Data           hexutil.Bytes  `json:"data"`
+	Operation      uint8          `json:"operation"`
+	SafeTxGas      *big.Int       `json:"safeTxGas"`
+	BaseGas        *big.Int       `json:"baseGas"`
+	GasPrice       *big.Int       `json:"gasPrice"`
+	GasToken       common.Address `json:"gasToken"`
+	RefundReceiver common.Address `json:"refundReceiver"`
+	Nonce          *big.Int       `json:"nonce"`
+}
+
+// SafeTx returns the batch as a single SafeTx: the call itself if there is only
+// one, otherwise a delegatecall to MultiSendCallOnly.
+func (b *SafeBatch) SafeTx() (*SafeTx, error) {
+	if len(b.Calls) == 0 {
+		return nil, errors.New("empty safe batch")
+	}
+	tx := &SafeTx{
+		SafeTxGas: new(big.Int),
+		BaseGas:   new(big.Int),
+		GasPrice:  new(big.Int),
+		Nonce:     new(big.Int),
+	}
+	if b.Nonce != nil {
+		tx.Nonce.Set(b.Nonce)
+	}
+	if len(b.Calls) == 1 {
+		c := b.Calls[0]
+		tx.To, tx.Value, tx.Data, tx.Operation = c.To, c.Value, c.Data, SafeOperationCall
+		return tx, nil
+	}
+	var packed []byte
+	for _, c := range b.Calls {
+		packed = append(packed, SafeOperationCall)
+		packed = append(packed, c.To.Bytes()...)
+		packed = append(packed, math.U256Bytes(new(big.Int).Set(c.Value))...)
+		packed = append(packed, math.U256Bytes(big.NewInt(int64(len(c.Data))))...)
+		packed = append(packed, c.Data...)
+	}
+	data, err := multiSendABI.Pack("multiSend", packed)
+	if err != nil {
+		return nil, err
+	}
+	tx.To, tx.Value, tx.Data, tx.Operation = MultiSendCallOnly, new(big.Int), data, SafeOperationDelegateCall
+	return tx, nil
+}
+
+// Hash computes the EIP-712 SafeTx hash the owners sign, for Safe v1.3.0 and later.
+func (tx *SafeTx) Hash(chainId *big.Int, safe common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, safeDomainSeparator(chainId, safe), tx.structHash())
+}
+
+// safeDomainSeparator is the EIP-712 domain of the Safe, v1.3.0 binds it to
+// the chain.
+func safeDomainSeparator(chainId *big.Int, safe common.Address) []byte {
+	return crypto.Keccak256(
+		safeDomainTypeHash.Bytes(),
+		math.U256Bytes(new(big.Int).Set(chainId)),
+		common.LeftPadBytes(safe.Bytes(), 32),
+	)
+}
+
+// structHash is the EIP-712 hash of the SafeTx message.
+func (tx *SafeTx) structHash() []byte {
+	return crypto.Keccak256(
+		safeTxTypeHash.Bytes(),
+		common.LeftPadBytes(tx.To.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(tx.Value)),
+		crypto.Keccak256(tx.Data),
+		common.LeftPadBytes([]byte{tx.Operation}, 32),
+		math.U256Bytes(new(big.Int).Set(tx.SafeTxGas)),
+		math.U256Bytes(new(big.Int).Set(tx.BaseGas)),
+		math.U256Bytes(new(big.Int).Set(tx.GasPrice)),
+		common.LeftPadBytes(tx.GasToken.Bytes(), 32),
+		common.LeftPadBytes(tx.RefundReceiver.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(tx.Nonce)),
+	)
+}
+
+type txBuilderBatch struct {
+	Version      string                 `json:"version"`
+	ChainID      string                 `json:"chainId"`
+	CreatedAt    int64                  `json:"createdAt"`
+	Meta         txBuilderMeta          `json:"meta"`
+	Transactions []txBuilderTransaction `json:"transactions"`
+}
+
+type txBuilderMeta struct {
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	TxBuilderVersion       string `json:"txBuilderVersion"`
+	CreatedFromSafeAddress string `json:"createdFromSafeAddress"`
+}
+
+type txBuilderTransaction struct {
+	To             string      `json:"to"`
+	Value          string      `json:"value"`
+	Data           string      `json:"data"`
+	ContractMethod interface{} `json:"contractMethod"`
+}
+
+// TxBuilderJSON encodes the batch for import into the Safe Transaction Builder app.
+func (b *SafeBatch) TxBuilderJSON(name string) ([]byte, error) {
+	batch := txBuilderBatch{
+		Version:   "1.0",
+		ChainID:   b.ChainID.String(),
+		CreatedAt: time.Now().UnixNano() / int64(time.Millisecond),
+		Meta: txBuilderMeta{
+			Name:             name,
+			TxBuilderVersion: "1.8.0",
+		},
+	}
+	if b.Safe != (common.Address{}) {
+		batch.Meta.CreatedFromSafeAddress = b.Safe.Hex()
+	}
+	var methods []string
+	for _, c := range b.Calls {
+		batch.Transactions = append(batch.Transactions, txBuilderTransaction{
+			To:    c.To.Hex(),
+			Value: c.Value.String(),
+			Data:  hexutil.Encode(c.Data),
+		})
+		methods = append(methods, c.Method)
+	}
+	batch.Meta.Description = strings.Join(methods, ", ")
+	return json.MarshalIndent(batch, "", "  ")
+}
+
+// StartSafeBatch makes the admin methods collect their calls for the Safe at
+// safe, rather than sending transactions.
+func (n *Node) StartSafeBatch(safe common.Address) {
+	n.safe = &SafeBatch{Safe: safe}
+}
+
+// FinishSafeBatch returns the collected batch, with the chain id filled in. If
+// nonce is nil, the current nonce of the Safe is read from chain.
+func (n *Node) FinishSafeBatch(ctx context.Context, nonce *big.Int) (*SafeBatch, error) {
+	if n.safe == nil {
+		return nil, errors.New("no safe batch started")
+	}
+	b := n.safe
+	n.safe = nil
+	var err error
+	b.ChainID, err = n.chainID(ctx)
+	if err != nil {
+		n.Sugar.Errorf("Get chainId error: %s", err)
+		return nil, err
+	}
+	if nonce == nil && b.Safe != (common.Address{}) {
+		nonce, err = n.safeNonce(ctx, b.Safe)
+		if err != nil {
+			n.Sugar.Errorf("read safe nonce error: %s", err)
+			return nil, err
+		}
+	}
+	b.Nonce = nonce
+	return b, nil
+}
+
+func (n *Node) safeNonce(ctx context.Context, safe common.Address) (*big.Int, error) {
+	data, err := safeNonceABI.Pack("nonce")
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.backend.CallContract(ctx, ethereum.CallMsg{To: &safe, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := safeNonceABI.Unpack("nonce", out)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a safe: %w", safe, err)
+	}
+	return res[0].(*big.Int), nil
+}
+
+// WriteTxBuilder writes the batch as Transaction Builder json into filename.
+func (b *SafeBatch) WriteTxBuilder(filename, name string) error {
+	data, err := b.TxBuilderJSON(name)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
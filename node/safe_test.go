@@ -0,0 +1,130 @@
+package node
+
+import (
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"math/big"
+	"testing"
+)
+
+func TestSafeTypeHashes(t *testing.T) {
+	// DOMAIN_SEPARATOR_TYPEHASH and SAFE_TX_TYPEHASH of GnosisSafe.sol v1.3.0
+	if want := common.HexToHash("0x47e79534a245952e8b16893a336b85a3d9ea9fa8c573f3d803afb92a79469218"); safeDomainTypeHash != want {
+		t.Errorf("domain type hash %s, want %s", safeDomainTypeHash.Hex(), want.Hex())
+	}
+	if want := common.HexToHash("0xbb8310d486368db6bd6f849402fdd73ad53d316b5a4b2644ad6efe0f941286d8"); safeTxTypeHash != want {
+		t.Errorf("SafeTx type hash %s, want %s", safeTxTypeHash.Hex(), want.Hex())
+	}
+}
+
+func TestSafeTxHash(t *testing.T) {
+	// a transaction of the Safe transaction service, from the go-ethereum
+	// signer tests; its Safe is older than v1.3.0, with no chain id in the domain
+	safe := common.HexToAddress("0x25a6c4BBd32B2424A9c99aEB0584Ad12045382B3")
+	tx := &SafeTx{
+		To:        common.HexToAddress("0x9eE457023bB3De16D51A003a247BaEaD7fce313D"),
+		Value:     big.NewInt(20000000000000000),
+		SafeTxGas: big.NewInt(27845),
+		BaseGas:   new(big.Int),
+		GasPrice:  new(big.Int),
+		Nonce:     big.NewInt(3),
+	}
+	legacyDomain := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(address verifyingContract)")),
+		common.LeftPadBytes(safe.Bytes(), 32),
+	)
+	got := crypto.Keccak256Hash([]byte{0x19, 0x01}, legacyDomain, tx.structHash())
+	if want := common.HexToHash("0x28bae2bd58d894a1d9b69e5e9fde3570c4b98a6fc5499aefb54fb830137e831f"); got != want {
+		t.Errorf("safeTxHash %s, want %s", got.Hex(), want.Hex())
+	}
+
+	// the v1.3.0 hash agrees with the EIP-712 encoder of go-ethereum's signer
+	chainId := big.NewInt(4)
+	tx.Data = common.FromHex("0x8456cb59")
+	typed := core.TypedData{
+		Types: core.Types{
+			"EIP712Domain": []core.Type{{Name: "chainId", Type: "uint256"}, {Name: "verifyingContract", Type: "address"}},
+			"SafeTx": []core.Type{
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "data", Type: "bytes"},
+				{Name: "operation", Type: "uint8"},
+				{Name: "safeTxGas", Type: "uint256"},
+				{Name: "baseGas", Type: "uint256"},
+				{Name: "gasPrice", Type: "uint256"},
+				{Name: "gasToken", Type: "address"},
+				{Name: "refundReceiver", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SafeTx",
+		Domain:      core.TypedDataDomain{ChainId: math.NewHexOrDecimal256(chainId.Int64()), VerifyingContract: safe.Hex()},
+		Message: core.TypedDataMessage{
+			"to":             tx.To.Hex(),
+			"value":          tx.Value.String(),
+			"data":           "0x8456cb59",
+			"operation":      "0",
+			"safeTxGas":      tx.SafeTxGas.String(),
+			"baseGas":        "0",
+			"gasPrice":       "0",
+			"gasToken":       common.Address{}.Hex(),
+			"refundReceiver": common.Address{}.Hex(),
+			"nonce":          tx.Nonce.String(),
+		},
+	}
+	domain, err := typed.HashStruct("EIP712Domain", typed.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := typed.HashStruct(typed.PrimaryType, typed.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(domain, safeDomainSeparator(chainId, safe)) {
+		t.Errorf("domain separator %x, want %x", safeDomainSeparator(chainId, safe), domain)
+	}
+	if want := crypto.Keccak256Hash([]byte{0x19, 0x01}, domain, message); tx.Hash(chainId, safe) != want {
+		t.Errorf("hash %s, want %s", tx.Hash(chainId, safe).Hex(), want.Hex())
+	}
+}
+
+func TestSafeBatchMultiSend(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000c0de0")
+	b := &SafeBatch{Nonce: big.NewInt(7)}
+	if err := b.Add(contract, "pause"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := b.SafeTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.To != contract || tx.Operation != SafeOperationCall || !bytes.Equal(tx.Data, common.FromHex("0x8456cb59")) || tx.Nonce.Int64() != 7 {
+		t.Errorf("one call: %+v, want the call itself", tx)
+	}
+
+	if err = b.Add(contract, "setPhase", int8(2)); err != nil {
+		t.Fatal(err)
+	}
+	if tx, err = b.SafeTx(); err != nil {
+		t.Fatal(err)
+	}
+	if tx.To != MultiSendCallOnly || tx.Operation != SafeOperationDelegateCall || tx.Value.Sign() != 0 {
+		t.Fatalf("two calls: %+v, want a delegatecall to MultiSendCallOnly", tx)
+	}
+	// multiSend(bytes) of operation (1 byte), to (20), value (32), data length
+	// (32) and data, for each call
+	word := func(n int64) string { return common.Bytes2Hex(math.U256Bytes(big.NewInt(n))) }
+	setPhase := common.Bytes2Hex(carABI.Methods["setPhase"].ID) + word(2)
+	packed := "00" + common.Bytes2Hex(contract.Bytes()) + word(0) + word(4) + "8456cb59" +
+		"00" + common.Bytes2Hex(contract.Bytes()) + word(0) + word(36) + setPhase
+	want := "8d80ff0a" + word(32) + word(int64(len(packed)/2)) + packed
+	if pad := len(packed) / 2 % 32; pad != 0 {
+		want += common.Bytes2Hex(make([]byte, 32-pad))
+	}
+	if got := common.Bytes2Hex(tx.Data); got != want {
+		t.Errorf("multiSend data\n%s\nwant\n%s", got, want)
+	}
+}
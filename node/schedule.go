@@ -0,0 +1,428 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheduled actions.
+const (
+	ActionSetPhase = "setPhase"
+	ActionPause    = "pause"
+	ActionUnpause  = "unpause"
+	ActionWithdraw = "withdraw"
+)
+
+// Outcomes of a scheduled action.
+const (
+	ScheduleDone    = "done"
+	ScheduleSkipped = "skipped"
+	ScheduleFailed  = "failed"
+	// ScheduleSent is an entry whose transaction is sent and not mined yet, as
+	// saved to the progress file meanwhile.
+	ScheduleSent = "sent"
+)
+
+// ScheduleEntry is an action to send at a time.
+type ScheduleEntry struct {
+	Line   int
+	At     time.Time
+	Action string
+	// Phase is the argument of setPhase.
+	Phase int8
+}
+
+// Key identifies the entry in the progress file, it doesn't change when lines
+// are added or removed around it.
+func (e ScheduleEntry) Key() string {
+	key := e.At.UTC().Format(time.RFC3339) + " " + e.Action
+	if e.Action == ActionSetPhase {
+		key += " " + strconv.Itoa(int(e.Phase))
+	}
+	return key
+}
+
+func (e ScheduleEntry) String() string {
+	if e.Action == ActionSetPhase {
+		return fmt.Sprintf("setPhase %d", e.Phase)
+	}
+	return e.Action
+}
+
+// LoadSchedule reads a schedule file, one action per line:
+//
+//	2021-11-20T12:00:00Z setPhase 1
+//	2021-11-21T12:00:00+08:00 pause
+//
+// The time is RFC 3339 or unix seconds; the actions are setPhase N, pause,
+// unpause and withdraw. Blank lines and lines starting with # are skipped.
+// The entries are returned in time order, file order for the same time.
+func LoadSchedule(filename string) ([]ScheduleEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ScheduleEntry
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		e, err := parseScheduleLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, line, err)
+		}
+		e.Line = line
+		if first, ok := seen[e.Key()]; ok {
+			return nil, fmt.Errorf("%s line %d: same action at the same time as line %d", filename, line, first)
+		}
+		seen[e.Key()] = line
+		entries = append(entries, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return entries, nil
+}
+
+func parseScheduleLine(text string) (ScheduleEntry, error) {
+	var e ScheduleEntry
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return e, fmt.Errorf("want \"<time> <action>\", got %q", text)
+	}
+	if t, err := time.Parse(time.RFC3339, fields[0]); err == nil {
+		e.At = t
+	} else if sec, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+		e.At = time.Unix(sec, 0)
+	} else {
+		return e, fmt.Errorf("bad time %q, want RFC 3339 or unix seconds", fields[0])
+	}
+	e.Action = fields[1]
+	args := fields[2:]
+	switch e.Action {
+	case ActionSetPhase:
+		if len(args) != 1 {
+			return e, errors.New("setPhase takes the phase")
+		}
+		phase, err := strconv.ParseInt(args[0], 0, 8)
+		if err != nil {
+			return e, fmt.Errorf("bad phase %q", args[0])
+		}
+		e.Phase = int8(phase)
+	case ActionPause, ActionUnpause, ActionWithdraw:
+		if len(args) != 0 {
+			return e, fmt.Errorf("%s takes no argument", e.Action)
+		}
+	default:
+		return e, fmt.Errorf("unknown action %q, want setPhase, pause, unpause or withdraw", e.Action)
+	}
+	return e, nil
+}
+
+// ScheduleRecord is the progress of an entry, kept in the progress file.
+type ScheduleRecord struct {
+	Status   string    `json:"status"`
+	Time     time.Time `json:"time"`
+	Attempts int       `json:"attempts"`
+	Note     string    `json:"note,omitempty"`
+	// Tx is the transaction of an entry sent, or failed while its wait timed
+	// out or was interrupted: it may still be mined, so the next run waits for
+	// it again before sending anything.
+	Tx string `json:"tx,omitempty"`
+}
+
+// ScheduleResult is the outcome of an entry, as reported to ScheduleOptions.Notify
+// and to the notifier of the node.
+type ScheduleResult struct {
+	Entry    ScheduleEntry
+	Status   string
+	Attempts int
+	// Note tells why an entry was skipped.
+	Note string
+	Err  error
+	// Pending is the transaction sent and not known to be mined, if the entry
+	// failed with its wait timed out or interrupted.
+	Pending *types.Transaction
+	// Late is how long after its time the entry was run.
+	Late time.Duration
+}
+
+// ScheduleOptions controls RunSchedule.
+type ScheduleOptions struct {
+	// Progress is the file the outcome of every entry is saved to, entries done
+	// or skipped there are not run again.
+	Progress string
+	// Retries is how many times a failed action is tried again.
+	Retries int
+	// RetryDelay is the wait before a retry.
+	RetryDelay time.Duration
+	// Notify is told the outcome of every entry.
+	Notify func(ScheduleResult)
+}
+
+// LoadScheduleProgress reads the progress file, an empty progress if it
+// doesn't exist yet.
+func LoadScheduleProgress(filename string) (map[string]ScheduleRecord, error) {
+	progress := make(map[string]ScheduleRecord)
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &progress); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return progress, nil
+}
+
+// saveScheduleProgress replaces the progress file, through a temporary file
+// so a crash doesn't leave it half written.
+func saveScheduleProgress(filename string, progress map[string]ScheduleRecord) error {
+	b, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(append(b, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// RunSchedule sends every entry not done yet at its time, entries already due
+// are sent at once in order. Before each action the signer must still be the
+// owner, and an action already in effect, e.g. setPhase to the current phase,
+// is skipped. A failed action is retried, except when it reverted or the
+// signer is not the owner; if it still fails RunSchedule stops, so the later
+// entries don't run out of order, and the entry is tried again on the next run.
+func (n *Node) RunSchedule(ctx context.Context, entries []ScheduleEntry, opts ScheduleOptions) error {
+	progress, err := LoadScheduleProgress(opts.Progress)
+	if err != nil {
+		n.Sugar.Errorf("load schedule progress error: %s", err)
+		return err
+	}
+	notify := func(r ScheduleResult) {
+		if opts.Notify != nil {
+			opts.Notify(r)
+		}
+	}
+	for _, e := range entries {
+		r, ok := progress[e.Key()]
+		if ok && r.Status != ScheduleFailed && r.Status != ScheduleSent {
+			continue
+		}
+		var pending *types.Transaction
+		if r.Tx != "" {
+			tx, _, err := n.backend.TransactionByHash(ctx, common.HexToHash(r.Tx))
+			if err != nil && !errors.Is(err, ethereum.NotFound) {
+				n.Sugar.Errorf("get tx %s error: %s", r.Tx, err)
+				return err
+			}
+			pending = tx
+		}
+		if wait := time.Until(e.At); wait > 0 {
+			n.Sugar.Infof("next: %s at %s, in %s", e, e.At.Format(time.RFC3339), wait.Round(time.Second))
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		// the transaction is saved once sent, so a crash doesn't send it twice
+		sent := func(r ScheduleResult) {
+			progress[e.Key()] = r.record()
+			if err := saveScheduleProgress(opts.Progress, progress); err != nil {
+				n.Sugar.Errorf("save schedule progress error: %s", err)
+			}
+		}
+		result := n.runScheduled(ctx, e, pending, opts, sent)
+		record := result.record()
+		progress[e.Key()] = record
+		if err = saveScheduleProgress(opts.Progress, progress); err != nil {
+			n.Sugar.Errorf("save schedule progress error: %s", err)
+			return err
+		}
+		if errors.Is(result.Err, context.Canceled) {
+			return result.Err
+		}
+		notify(result)
+		event := Event{Kind: EventSchedule, Action: e.String(), Status: result.Status, Attempts: result.Attempts, Note: record.Note}
+		if result.Pending != nil {
+			event.Tx = result.Pending.Hash()
+		}
+		n.notify(ctx, event)
+		if result.Err != nil {
+			return fmt.Errorf("line %d %s: %w", e.Line, e, result.Err)
+		}
+	}
+	return nil
+}
+
+func (r ScheduleResult) record() ScheduleRecord {
+	record := ScheduleRecord{Status: r.Status, Time: time.Now(), Attempts: r.Attempts, Note: r.Note}
+	if r.Err != nil {
+		record.Note = r.Err.Error()
+	}
+	if r.Pending != nil {
+		record.Tx = r.Pending.Hash().String()
+	}
+	return record
+}
+
+// runScheduled runs one entry with its checks and retries. When the wait for
+// the transaction timed out, the retry waits for that transaction again, the
+// fee bump policy speeding it up, rather than sending the action a second
+// time while the first may still be mined. Only once it is dropped or its
+// nonce is taken by another one is the entry checked and sent again. pending
+// is such a transaction left by the previous run, if any. sent, if not nil,
+// is told of every transaction sent, before it is waited for.
+func (n *Node) runScheduled(ctx context.Context, e ScheduleEntry, pending *types.Transaction, opts ScheduleOptions, sent func(ScheduleResult)) ScheduleResult {
+	result := ScheduleResult{Entry: e, Late: time.Since(e.At)}
+	for {
+		result.Attempts++
+		var err error
+		waited := pending
+		if pending != nil {
+			n.Sugar.Infof("%s: wait again for tx %s", e, pending.Hash().String())
+			if _, err = n.waitMinedNotified(ctx, e.Action, pending); err == nil {
+				n.Sugar.Infof("%s done", e)
+				result.Status = ScheduleDone
+				return result
+			}
+		} else {
+			var note string
+			note, err = n.checkScheduled(ctx, e)
+			if err == nil && note != "" {
+				n.Sugar.Infof("%s skipped: %s", e, note)
+				result.Status, result.Note = ScheduleSkipped, note
+				return result
+			}
+			if err == nil {
+				err = n.sendScheduled(ctx, e, func(tx *types.Transaction) {
+					waited = tx
+					if sent != nil {
+						sent(ScheduleResult{Entry: e, Status: ScheduleSent, Attempts: result.Attempts, Pending: tx, Late: result.Late})
+					}
+				})
+				if err == nil {
+					n.Sugar.Infof("%s done", e)
+					result.Status = ScheduleDone
+					return result
+				}
+			}
+		}
+		pending = nil
+		var timeout *WaitTimeoutError
+		if errors.As(err, &timeout) {
+			pending = timeout.Tx
+		} else if errors.Is(err, context.Canceled) {
+			pending = waited
+		}
+		result.Status, result.Err, result.Pending = ScheduleFailed, err, pending
+		if result.Attempts > opts.Retries || errors.Is(err, ErrReverted) || errors.Is(err, ErrNotOwner) || ctx.Err() != nil {
+			n.Sugar.Errorf("%s error: %s", e, err)
+			return result
+		}
+		n.Sugar.Warnf("%s attempt %d failed, retry in %s: %s", e, result.Attempts, opts.RetryDelay, err)
+		select {
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			return result
+		case <-time.After(opts.RetryDelay):
+		}
+	}
+}
+
+// checkScheduled checks the signer is the owner and tells why the entry is to
+// be skipped, if it is.
+func (n *Node) checkScheduled(ctx context.Context, e ScheduleEntry) (string, error) {
+	if err := n.CheckOwner(ctx, common.Address{}); err != nil {
+		return "", err
+	}
+	switch e.Action {
+	case ActionSetPhase:
+		phase, err := n.Phase(ctx)
+		if err != nil {
+			n.Sugar.Errorf("get phase error: %s", err)
+			return "", err
+		}
+		if phase == e.Phase {
+			return fmt.Sprintf("already in phase %d", phase), nil
+		}
+	case ActionPause, ActionUnpause:
+		paused, err := n.Paused(ctx)
+		if err != nil {
+			n.Sugar.Errorf("check paused error: %s", err)
+			return "", err
+		}
+		if paused && e.Action == ActionPause {
+			return "already paused", nil
+		}
+		if !paused && e.Action == ActionUnpause {
+			return "already non-paused", nil
+		}
+	case ActionWithdraw:
+		balance, err := n.Balance(ctx, n.contract)
+		if err != nil {
+			n.Sugar.Errorf("get contract balance error: %s", err)
+			return "", err
+		}
+		if balance.Sign() == 0 {
+			return "nothing to withdraw", nil
+		}
+	}
+	return "", nil
+}
+
+// sendScheduled sends the action of e and waits for it, telling sent of the
+// transaction in between.
+func (n *Node) sendScheduled(ctx context.Context, e ScheduleEntry, sent func(*types.Transaction)) error {
+	var params []interface{}
+	if e.Action == ActionSetPhase {
+		params = append(params, e.Phase)
+	}
+	if n.dryRun || n.safe != nil || n.unsignedOut != "" {
+		return n.transact(ctx, e.Action, params...)
+	}
+	tx, err := n.submit(ctx, e.Action, params...)
+	if err != nil {
+		return err
+	}
+	sent(tx)
+	_, err = n.waitNotified(ctx, e.Action, tx)
+	return err
+}
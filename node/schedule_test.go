@@ -0,0 +1,236 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSchedule(t *testing.T, lines ...string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "schedule.txt")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func unix(sec int64) string {
+	return strconv.FormatInt(sec, 10)
+}
+
+func TestLoadSchedule(t *testing.T) {
+	entries, err := LoadSchedule(writeSchedule(t,
+		"# launch",
+		"2021-11-21T12:00:00Z setPhase 2",
+		"",
+		"1637409600 pause",
+		"2021-11-20T20:00:00+08:00 setPhase 1",
+		"2021-11-22T00:00:00Z withdraw",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Key())
+	}
+	want := []string{
+		"2021-11-20T12:00:00Z pause",
+		"2021-11-20T12:00:00Z setPhase 1",
+		"2021-11-21T12:00:00Z setPhase 2",
+		"2021-11-22T00:00:00Z withdraw",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if entries[0].Line != 4 {
+		t.Errorf("pause at line %d, want 4", entries[0].Line)
+	}
+
+	for _, bad := range []string{
+		"tomorrow pause",
+		"2021-11-20T12:00:00Z mint",
+		"2021-11-20T12:00:00Z setPhase",
+		"2021-11-20T12:00:00Z setPhase 200",
+		"2021-11-20T12:00:00Z pause now",
+	} {
+		if _, err := LoadSchedule(writeSchedule(t, bad)); err == nil {
+			t.Errorf("%q loaded", bad)
+		}
+	}
+	if _, err := LoadSchedule(writeSchedule(t, "1637409600 pause", "2021-11-20T12:00:00Z pause")); err == nil {
+		t.Error("the same action at the same time loaded twice")
+	}
+}
+
+func TestRunSchedule(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour).Unix()
+	entries, err := LoadSchedule(writeSchedule(t,
+		unix(past)+" setPhase 1",
+		unix(past+1)+" pause",
+		unix(past+2)+" pause",
+		unix(past+3)+" unpause",
+		unix(past+4)+" withdraw",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := filepath.Join(t.TempDir(), "progress.json")
+	var results []ScheduleResult
+	opts := ScheduleOptions{Progress: progress, Retries: 2, Notify: func(r ScheduleResult) {
+		results = append(results, r)
+	}}
+	if err = h.owner.RunSchedule(ctx, entries, opts); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	if got, want := strings.Join(statuses, " "), "done done skipped done skipped"; got != want {
+		t.Errorf("statuses = %s, want %s", got, want)
+	}
+	if phase, _ := h.owner.Phase(ctx); phase != 1 {
+		t.Errorf("phase = %d, want 1", phase)
+	}
+	if paused, _ := h.owner.Paused(ctx); paused {
+		t.Error("paused after the schedule")
+	}
+
+	// a restart sends nothing again
+	saved, err := LoadScheduleProgress(progress)
+	if err != nil || len(saved) != len(entries) {
+		t.Fatalf("progress = %v, %v; want %d entries", saved, err, len(entries))
+	}
+	before := h.backend.Blockchain().CurrentBlock().NumberU64()
+	results = nil
+	if err = h.owner.RunSchedule(ctx, entries, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 || h.backend.Blockchain().CurrentBlock().NumberU64() != before {
+		t.Errorf("restart ran %d entries", len(results))
+	}
+}
+
+func TestRunScheduleNotOwner(t *testing.T) {
+	h := newHarness(t)
+	past := unix(time.Now().Add(-time.Minute).Unix())
+	entries, err := LoadSchedule(writeSchedule(t, past+" setPhase 1", past+" pause"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := filepath.Join(t.TempDir(), "progress.json")
+	var results []ScheduleResult
+	err = h.other.RunSchedule(context.Background(), entries, ScheduleOptions{Progress: progress, Retries: 3, Notify: func(r ScheduleResult) {
+		results = append(results, r)
+	}})
+	if !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("err = %v, want ErrNotOwner", err)
+	}
+	if len(results) != 1 || results[0].Status != ScheduleFailed || results[0].Attempts != 1 {
+		t.Fatalf("results = %+v, want setPhase failed once and pause not run", results)
+	}
+	saved, _ := LoadScheduleProgress(progress)
+	if r := saved[entries[0].Key()]; r.Status != ScheduleFailed {
+		t.Errorf("progress of setPhase = %+v, want failed", r)
+	}
+}
+
+func TestRunScheduleTimeout(t *testing.T) {
+	backend, cfg := newBare(t)
+	cfg.WaitTimeout = 1
+	n := NewWithBackend(cfg, backend)
+	ctx := context.Background()
+	if err := n.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e := ScheduleEntry{Line: 1, At: time.Now(), Action: ActionPause}
+	tx, err := n.send(ctx, "pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not mined in time, the transaction is kept rather than sent again
+	r := n.runScheduled(ctx, e, tx, ScheduleOptions{}, nil)
+	if r.Status != ScheduleFailed || !errors.Is(r.Err, ErrWaitTimeout) || r.Pending == nil || r.Pending.Hash() != tx.Hash() {
+		t.Fatalf("result = %+v, want a timeout with tx %s pending", r, tx.Hash().String())
+	}
+
+	// mined before the next run, which waits for it and sends nothing new
+	progress := filepath.Join(t.TempDir(), "progress.json")
+	if err = saveScheduleProgress(progress, map[string]ScheduleRecord{
+		e.Key(): {Status: ScheduleFailed, Attempts: r.Attempts, Tx: r.Pending.Hash().String()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	hooks, url := newHooks(t)
+	nt, err := NewNotifier(NotifyConfig{Channels: []NotifyChannel{{Type: ChannelWebhook, URL: url + "/webhook", Events: []string{EventSchedule}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.SetNotifier(nt)
+	backend.Commit()
+	if err = n.RunSchedule(ctx, []ScheduleEntry{e}, ScheduleOptions{Progress: progress}); err != nil {
+		t.Fatal(err)
+	}
+	posts := hooks.take("/webhook")
+	if len(posts) != 1 || posts[0]["action"] != "pause" || posts[0]["status"] != ScheduleDone {
+		t.Errorf("webhook got %v, want pause done", posts)
+	}
+	account, _ := n.signerAccount()
+	if nonce, _ := backend.PendingNonceAt(ctx, account.Address()); nonce != 1 {
+		t.Errorf("%d transactions sent, want 1", nonce)
+	}
+}
+
+func TestRunScheduleInterrupted(t *testing.T) {
+	n, c := newStateNode(t)
+	account, _ := n.signerAccount()
+	c.owner = account.Address()
+	e := ScheduleEntry{Line: 1, At: time.Now(), Action: ActionPause}
+	progress := filepath.Join(t.TempDir(), "progress.json")
+
+	// stopped while waiting: the transaction is in the progress file as soon
+	// as it is sent, and stays there once interrupted
+	ctx, cancel := context.WithCancel(context.Background())
+	var sent ScheduleRecord
+	n.SetProgress(func(WaitStatus) {
+		saved, _ := LoadScheduleProgress(progress)
+		sent = saved[e.Key()]
+		cancel()
+	})
+	err := n.RunSchedule(ctx, []ScheduleEntry{e}, ScheduleOptions{Progress: progress})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if sent.Status != ScheduleSent || sent.Tx == "" {
+		t.Fatalf("progress while waiting = %+v, want sent with its tx", sent)
+	}
+	saved, _ := LoadScheduleProgress(progress)
+	if r := saved[e.Key()]; r.Status != ScheduleFailed || r.Tx != sent.Tx {
+		t.Fatalf("progress once interrupted = %+v, want failed with tx %s", r, sent.Tx)
+	}
+
+	// the restart waits for it rather than pausing again
+	n.SetProgress(nil)
+	c.Backend.(*backends.SimulatedBackend).Commit()
+	if err = n.RunSchedule(context.Background(), []ScheduleEntry{e}, ScheduleOptions{Progress: progress}); err != nil {
+		t.Fatal(err)
+	}
+	if nonce, _ := c.PendingNonceAt(context.Background(), account.Address()); nonce != 1 {
+		t.Errorf("%d transactions sent, want 1", nonce)
+	}
+	saved, _ = LoadScheduleProgress(progress)
+	if r := saved[e.Key()]; r.Status != ScheduleDone {
+		t.Errorf("progress after the restart = %+v, want done", r)
+	}
+}
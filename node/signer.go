@@ -0,0 +1,151 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"io/ioutil"
+	"math/big"
+)
+
+// Signer signs transactions on behalf of a single account.
+type Signer interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainId *big.Int) (*types.Transaction, error)
+}
+
+// ErrNoSigner is returned when a transaction is requested but no key is configured.
+var ErrNoSigner = errors.New("no signer configured")
+
+// ErrNotOwner is returned when the admin account is not the contract owner.
+var ErrNotOwner = errors.New("not the contract owner")
+
+// ErrWatchOnly is returned when signing with an account known by address only.
+var ErrWatchOnly = errors.New("watch-only account can not sign")
+
+// ErrReadOnly is returned when a read-only Node is asked for its account.
+var ErrReadOnly = errors.New("read-only node, no key material loaded")
+
+// NewMnemonicSigner derives the account-th key from the mnemonic stored in filename,
+// along the derivation path template (see DerivationPath). The file must not be
+// readable by others.
+func NewMnemonicSigner(filename, template string, account int) (Signer, error) {
+	wallet, err := loadWallet(filename)
+	if err != nil {
+		return nil, err
+	}
+	path, err := DerivationPath(template, account)
+	if err != nil {
+		return nil, err
+	}
+	a, err := wallet.Derive(path, false)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := wallet.PrivateKey(a)
+	if err != nil {
+		return nil, err
+	}
+	return newKeySigner(privateKey), nil
+}
+
+// NewKeystoreSigner decrypts a keystore json file with password.
+func NewKeystoreSigner(filename, password string) (Signer, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(b, password)
+	if err != nil {
+		return nil, err
+	}
+	return newKeySigner(key.PrivateKey), nil
+}
+
+// addressSigner knows the account address only, it can prepare transactions but not sign them.
+type addressSigner common.Address
+
+func (s addressSigner) Address() common.Address {
+	return common.Address(s)
+}
+
+func (s addressSigner) SignTx(*types.Transaction, *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
+
+// keySigner signs with a local private key, e.g. one derived from the hd wallet.
+type keySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func newKeySigner(key *ecdsa.PrivateKey) *keySigner {
+	return &keySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *keySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *keySigner) SignTx(tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainId), s.key)
+}
+
+// ExternalSigner forwards transactions to an external signer speaking the clef
+// account_signTransaction API, over IPC or HTTP.
+type ExternalSigner struct {
+	ext     *external.ExternalSigner
+	account accounts.Account
+}
+
+// NewExternalSigner connects to the signer at endpoint. If from is empty, the
+// index-th account returned by account_list is used.
+func NewExternalSigner(endpoint, from string, index int) (*ExternalSigner, error) {
+	ext, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s := &ExternalSigner{ext: ext}
+	if from != "" {
+		if !common.IsHexAddress(from) {
+			return nil, fmt.Errorf("bad signer account %s", from)
+		}
+		s.account = accounts.Account{Address: common.HexToAddress(from), URL: ext.URL()}
+		return s, nil
+	}
+	list := ext.Accounts()
+	if len(list) == 0 {
+		return nil, errors.New("external signer has no accounts")
+	}
+	if index < 0 || index >= len(list) {
+		return nil, fmt.Errorf("external signer has %d accounts, account %d not found", len(list), index)
+	}
+	s.account = list[index]
+	return s, nil
+}
+
+func (s *ExternalSigner) Address() common.Address {
+	return s.account.Address
+}
+
+func (s *ExternalSigner) SignTx(tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	signed, err := s.ext.SignTx(s.account, tx, chainId)
+	if err != nil {
+		return nil, err
+	}
+	// never trust the signer blindly, make sure it signed for the right account
+	sender, err := types.Sender(types.LatestSignerForChainID(chainId), signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != s.account.Address {
+		return nil, fmt.Errorf("external signer signed as %s, want %s", sender, s.account.Address)
+	}
+	return signed, nil
+}
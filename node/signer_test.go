@@ -0,0 +1,62 @@
+package node
+
+import (
+	"github.com/cybercar-nft/go-cybercar/node/signertest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+func TestExternalSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	srv, err := signertest.NewServer(other, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	s, err := NewExternalSigner(srv.URL, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	if s.Address() != want {
+		t.Fatalf("account = %s, want %s", s.Address(), want)
+	}
+
+	chainId := big.NewInt(5)
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	txs := []*types.Transaction{
+		types.NewTransaction(3, to, big.NewInt(0), 21000, big.NewInt(1e9), []byte{0xca, 0xfe}),
+		types.NewTx(&types.DynamicFeeTx{
+			ChainID: chainId, Nonce: 4, To: &to, Gas: 21000,
+			GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(2e9),
+		}),
+	}
+	for _, tx := range txs {
+		signed, err := s.SignTx(tx, chainId)
+		if err != nil {
+			t.Fatalf("sign tx type %d: %s", tx.Type(), err)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainId), signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sender != want {
+			t.Errorf("sender = %s, want %s", sender, want)
+		}
+		if signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() {
+			t.Errorf("signed tx differs from request")
+		}
+	}
+
+	if _, err = NewExternalSigner(srv.URL, "", 2); err == nil {
+		t.Error("expected error for missing account")
+	}
+	if _, err = NewExternalSigner(srv.URL, "0xnotanaddress", 0); err == nil {
+		t.Error("expected error for bad account")
+	}
+}
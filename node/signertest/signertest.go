@@ -0,0 +1,90 @@
+// Package signertest provides a minimal in-process stand-in for clef, the
+// external signer, good enough to exercise node.ExternalSigner in tests.
+//
+// It answers account_version, account_list and account_signTransaction over
+// HTTP and signs everything it is asked to, so never use it with real keys.
+package signertest
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"net/http/httptest"
+)
+
+const version = "6.1.0"
+
+// Server is a running stand-in signer.
+type Server struct {
+	URL string
+
+	rpc  *rpc.Server
+	http *httptest.Server
+}
+
+// NewServer starts a stand-in signer holding keys.
+func NewServer(keys ...*ecdsa.PrivateKey) (*Server, error) {
+	api := &accountAPI{keys: make(map[common.Address]*ecdsa.PrivateKey)}
+	for _, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		api.keys[addr] = key
+		api.addresses = append(api.addresses, addr)
+	}
+	s := &Server{rpc: rpc.NewServer()}
+	if err := s.rpc.RegisterName("account", api); err != nil {
+		return nil, err
+	}
+	s.http = httptest.NewServer(s.rpc)
+	s.URL = s.http.URL
+	return s, nil
+}
+
+// Close shuts the signer down.
+func (s *Server) Close() {
+	s.http.Close()
+	s.rpc.Stop()
+}
+
+type signTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+type accountAPI struct {
+	keys      map[common.Address]*ecdsa.PrivateKey
+	addresses []common.Address
+}
+
+func (api *accountAPI) Version() string {
+	return version
+}
+
+func (api *accountAPI) List() []common.Address {
+	return api.addresses
+}
+
+func (api *accountAPI) SignTransaction(args apitypes.SendTxArgs, methodSelector *string) (*signTransactionResult, error) {
+	key, ok := api.keys[args.From.Address()]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %s", args.From.Address())
+	}
+	if args.ChainID == nil {
+		return nil, errors.New("chain id not specified")
+	}
+	signer := types.LatestSignerForChainID(args.ChainID.ToInt())
+	tx, err := types.SignTx(args.ToTransaction(), signer, key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTransactionResult{Raw: raw, Tx: tx}, nil
+}
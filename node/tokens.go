@@ -0,0 +1,156 @@
+package node
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultIPFSGateway resolves ipfs:// token uris.
+const DefaultIPFSGateway = "https://ipfs.io/ipfs/"
+
+// Token is one token of a holder, URI and metadata are only set if resolved.
+type Token struct {
+	ID    *big.Int `json:"id"`
+	URI   string   `json:"uri,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	Image string   `json:"image,omitempty"`
+	// Error is why the uri or metadata could not be resolved.
+	Error string `json:"error,omitempty"`
+}
+
+// Holding is what one owner holds at a block.
+type Holding struct {
+	Owner common.Address `json:"owner"`
+	// ENS is the primary name of the owner, only set if looked up.
+	ENS     string   `json:"ens,omitempty"`
+	Block   *big.Int `json:"block"`
+	Balance *big.Int `json:"balance"`
+	Tokens  []Token  `json:"tokens"`
+}
+
+// Tokens enumerates the tokens of every owner with tokenOfOwnerByIndex, at
+// block, the latest block if nil.
+func (n *Node) Tokens(ctx context.Context, owners []common.Address, block *big.Int) ([]Holding, error) {
+	block, err := n.PinBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	var holdings []Holding
+	for _, owner := range owners {
+		balance, err := n.nft.BalanceOf(opts, owner)
+		if err != nil {
+			n.Sugar.Errorf("balanceOf %s error: %s", owner.Hex(), err)
+			return nil, err
+		}
+		h := Holding{Owner: owner, Block: block, Balance: balance}
+		for i := int64(0); i < balance.Int64(); i++ {
+			id, err := n.nft.TokenOfOwnerByIndex(opts, owner, big.NewInt(i))
+			if err != nil {
+				n.Sugar.Errorf("tokenOfOwnerByIndex %s %d error: %s", owner.Hex(), i, err)
+				return nil, err
+			}
+			h.Tokens = append(h.Tokens, Token{ID: id})
+		}
+		holdings = append(holdings, h)
+	}
+	return holdings, nil
+}
+
+// ResolveTokens reads the tokenURI of every token at the block of its holding,
+// and if fetch, the name and image from the metadata it points to. Failures
+// are recorded per token in Token.Error.
+func (n *Node) ResolveTokens(ctx context.Context, holdings []Holding, fetch bool, gateway string) {
+	for i := range holdings {
+		opts := &bind.CallOpts{Context: ctx, BlockNumber: holdings[i].Block}
+		for j := range holdings[i].Tokens {
+			t := &holdings[i].Tokens[j]
+			uri, err := n.nft.TokenURI(opts, t.ID)
+			if err != nil {
+				t.Error = err.Error()
+				continue
+			}
+			t.URI = uri
+			if !fetch {
+				continue
+			}
+			m, err := FetchMetadata(ctx, uri, gateway)
+			if err != nil {
+				n.Sugar.Warnf("metadata of token %s error: %s", t.ID, err)
+				t.Error = err.Error()
+				continue
+			}
+			t.Name, t.Image = m.Name, m.Image
+		}
+	}
+}
+
+// Metadata is the part of the ERC-721 metadata json shown to holders.
+type Metadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+// FetchMetadata loads the metadata json from a http(s), ipfs or data uri.
+func FetchMetadata(ctx context.Context, uri, gateway string) (*Metadata, error) {
+	var body []byte
+	switch {
+	case strings.HasPrefix(uri, "data:"):
+		i := strings.Index(uri, ",")
+		if i < 0 {
+			return nil, errors.New("bad data uri")
+		}
+		header, data := uri[5:i], uri[i+1:]
+		if strings.HasSuffix(header, ";base64") {
+			b, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return nil, err
+			}
+			body = b
+		} else {
+			s, err := url.PathUnescape(data)
+			if err != nil {
+				return nil, err
+			}
+			body = []byte(s)
+		}
+	default:
+		if strings.HasPrefix(uri, "ipfs://") {
+			if gateway == "" {
+				gateway = DefaultIPFSGateway
+			}
+			uri = strings.TrimSuffix(gateway, "/") + "/" + strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("get %s: %s", uri, resp.Status)
+		}
+		if body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	}
+	m := &Metadata{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("bad metadata json: %w", err)
+	}
+	return m, nil
+}
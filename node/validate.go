@@ -0,0 +1,202 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/cybercar-nft/go-cybercar/cyber"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"go.uber.org/zap/zapcore"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ValidationError lists all the problems found in a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the config for typos before anything is dialed or loaded,
+// so they are reported as such and not as a confusing rpc or call error.
+func (c *Config) Validate() error {
+	return c.validate(true, true)
+}
+
+// validate checks the mnemonic file only if keys, a read-only Node never loads
+// it, and the contract only if contract, a deployer has none yet.
+func (c *Config) validate(keys, contract bool) error {
+	var problems []string
+	add := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	add(checkRPC("rpc", c.RPC))
+	if contract || c.Contract != "" {
+		add(checkAddress("contract", c.Contract))
+	}
+	for name, network := range c.Networks {
+		if network.RPC != "" {
+			add(checkRPC(fmt.Sprintf("networks.%s.rpc", name), network.RPC))
+		}
+		if network.Contract != "" {
+			add(checkAddress(fmt.Sprintf("networks.%s.contract", name), network.Contract))
+		}
+		if network.ChainID == 0 {
+			problems = append(problems, fmt.Sprintf("networks.%s.chainId missing", name))
+		}
+	}
+	if c.ENSRegistry != "" {
+		add(checkAddress("ensRegistry", c.ENSRegistry))
+	}
+	if keys && c.Mnemonic != "" {
+		add(checkSecretFile("mnemonic", c.Mnemonic))
+	}
+	if c.From != "" {
+		add(checkAddress("from", c.From))
+	}
+	if c.Signer != "" {
+		add(checkRPC("signer", c.Signer))
+	}
+	if c.SignerAccount != "" {
+		add(checkAddress("signerAccount", c.SignerAccount))
+	}
+	if c.Account < 0 {
+		problems = append(problems, fmt.Sprintf("account %d is negative", c.Account))
+	}
+	add(checkLog(c))
+	for i, channel := range c.Notify.Channels {
+		problems = append(problems, channel.validate(fmt.Sprintf("notify.channels[%d]", i))...)
+	}
+	if c.Notify.TransferBurst < 0 {
+		problems = append(problems, fmt.Sprintf("notify.transferBurst %d is negative", c.Notify.TransferBurst))
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkAddress requires a hex address, with a valid EIP-55 checksum if it is
+// mixed case.
+func checkAddress(field, s string) error {
+	if s == "" {
+		return fmt.Errorf("%s missing", field)
+	}
+	if !common.IsHexAddress(s) {
+		return fmt.Errorf("%s %q is not an address", field, s)
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) {
+		if want := common.HexToAddress(s).Hex(); "0x"+hex != want {
+			return fmt.Errorf("%s %s has a bad checksum, did you mean %s", field, s, want)
+		}
+	}
+	return nil
+}
+
+// checkRPC requires a http(s) or ws(s) url, or the path of an existing IPC socket.
+func checkRPC(field, s string) error {
+	if s == "" {
+		return fmt.Errorf("%s missing", field)
+	}
+	u, err := url.Parse(s)
+	if err == nil && u.Scheme != "" {
+		switch u.Scheme {
+		case "http", "https", "ws", "wss":
+			if u.Host == "" {
+				return fmt.Errorf("%s %q has no host", field, s)
+			}
+			return nil
+		}
+		if len(u.Scheme) > 1 { // not a windows drive letter
+			return fmt.Errorf("%s %q has unsupported scheme %s, use http, https, ws, wss or an IPC path", field, s, u.Scheme)
+		}
+	}
+	if _, err := os.Stat(s); err != nil {
+		return fmt.Errorf("%s %q is neither a url nor an IPC path: %w", field, s, err)
+	}
+	return nil
+}
+
+// checkSecretFile requires the file to exist, and on unix not to be
+// accessible by group or others.
+func checkSecretFile(field, filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("%s file: %w", field, err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%s file %s is a directory", field, filename)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s file %s has mode %s, readable by others, run chmod 600 %s", field, filename, fi.Mode().Perm(), filename)
+	}
+	return nil
+}
+
+// checkLog requires a known level and existing directories for the log files.
+func checkLog(c *Config) error {
+	if c.Log.Level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+			return fmt.Errorf("log level %q unknown, use debug, info, warn or error", c.Log.Level)
+		}
+	}
+	for _, out := range append(append([]string{}, c.Log.Outputs...), c.Log.Errors...) {
+		if out == "stdout" || out == "stderr" || strings.Contains(out, "://") {
+			continue
+		}
+		if dir := filepath.Dir(out); dir != "." {
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				return fmt.Errorf("log output %s: directory %s does not exist", out, dir)
+			}
+		}
+	}
+	return nil
+}
+
+// ContractInfo is what Probe found at the contract address.
+type ContractInfo struct {
+	ChainID *big.Int
+	Name    string
+	Symbol  string
+	Version string
+}
+
+// Probe dials rpc and makes sure a CyberCar contract responds at contract.
+func Probe(ctx context.Context, rpc, contract string) (*ContractInfo, error) {
+	ec, err := ethclient.DialContext(ctx, rpc)
+	if err != nil {
+		return nil, err
+	}
+	defer ec.Close()
+	info := &ContractInfo{}
+	if info.ChainID, err = ec.ChainID(ctx); err != nil {
+		return nil, err
+	}
+	car, err := cyber.NewCarCaller(common.HexToAddress(contract), ec)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	if info.Name, err = car.Name(opts); err != nil {
+		return nil, fmt.Errorf("contract %s on chain %s: name() failed: %w", contract, info.ChainID, err)
+	}
+	if info.Symbol, err = car.Symbol(opts); err != nil {
+		return nil, fmt.Errorf("contract %s on chain %s: symbol() failed: %w", contract, info.ChainID, err)
+	}
+	if info.Version, err = car.Version(opts); err != nil {
+		return nil, fmt.Errorf("contract %s on chain %s: version() failed: %w", contract, info.ChainID, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,259 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrReverted    = errors.New("transaction reverted")
+	ErrTxDropped   = errors.New("transaction dropped from the pool")
+	ErrTxReplaced  = errors.New("transaction replaced by another one with the same nonce")
+	ErrWaitTimeout = errors.New("timeout waiting for transaction")
+)
+
+// WaitTimeoutError is a Wait which timed out, with the last transaction sent,
+// which may still be mined.
+type WaitTimeoutError struct {
+	Tx      *types.Transaction
+	Timeout time.Duration
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("%s %s after %s", ErrWaitTimeout, e.Tx.Hash().String(), e.Timeout)
+}
+
+func (e *WaitTimeoutError) Unwrap() error {
+	return ErrWaitTimeout
+}
+
+// droppedTicks is how many polls in a row a transaction may be unknown to the
+// node before it is reported dropped, it may just not have propagated yet.
+const droppedTicks = 3
+
+// WaitBackend is what the Waiter needs from the chain.
+type WaitBackend interface {
+	bind.DeployBackend
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+}
+
+// Wait states reported to the progress function.
+const (
+	WaitPending    = "pending"
+	WaitReplacing  = "replacing"
+	WaitMinedState = "mined"
+	WaitConfirmed  = "confirmed"
+)
+
+// WaitStatus is the progress of a Wait.
+type WaitStatus struct {
+	Hash          common.Hash
+	State         string
+	Confirmations uint64
+	Want          uint64
+	Elapsed       time.Duration
+}
+
+func (s WaitStatus) String() string {
+	if s.State == WaitPending || s.State == WaitReplacing {
+		return fmt.Sprintf("tx %s %s, %s", s.Hash.String(), s.State, s.Elapsed.Round(time.Second))
+	}
+	return fmt.Sprintf("tx %s %s, %d/%d confirmations", s.Hash.String(), s.State, s.Confirmations, s.Want)
+}
+
+// Waiter waits for transactions with bind.WaitMined, until they have enough
+// confirmations, with an overall timeout and detection of dropped and replaced
+// transactions.
+type Waiter struct {
+	Backend WaitBackend
+	// Confirmations is the depth to wait for, 1 means mined.
+	Confirmations uint64
+	// Timeout is the overall deadline, 0 for none.
+	Timeout time.Duration
+	// Interval between polls, default one second.
+	Interval time.Duration
+	// Progress, if set, is called on every poll.
+	Progress func(WaitStatus)
+
+	// Bump, if set, is called when the last sent transaction has been pending
+	// for BumpAfter, and returns its replacement, which is then waited for too.
+	Bump      func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+	BumpAfter time.Duration
+	MaxBumps  int
+}
+
+// Wait waits for tx from account from, or any of its replacements sent by Bump,
+// and returns the receipt once it has the wanted confirmations.
+func (w *Waiter) Wait(ctx context.Context, from common.Address, tx *types.Transaction) (*types.Receipt, error) {
+	parent := ctx
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+	watchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	mined := make(chan *types.Receipt)
+	watch := func(tx *types.Transaction) {
+		go func() {
+			r, err := bind.WaitMined(watchCtx, w.Backend, tx)
+			if err != nil {
+				return
+			}
+			select {
+			case mined <- r:
+			case <-watchCtx.Done():
+			}
+		}()
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	want := w.Confirmations
+	if want == 0 {
+		want = 1
+	}
+	txs := []*types.Transaction{tx}
+	watch(tx)
+	start := time.Now()
+	lastSent := start
+	bumping := w.Bump != nil && w.BumpAfter > 0
+	unknown := 0
+	var receipt *types.Receipt
+	for {
+		select {
+		case <-ctx.Done():
+			if parent.Err() == nil {
+				return nil, &WaitTimeoutError{Tx: txs[len(txs)-1], Timeout: w.Timeout}
+			}
+			return nil, ctx.Err()
+		case r := <-mined:
+			if receipt == nil {
+				receipt = r
+			}
+		case <-ticker.C:
+			if receipt != nil {
+				break
+			}
+			// the nonce is used, but not by us if none of our receipts shows up
+			nonce, err := w.Backend.NonceAt(ctx, from, nil)
+			if err == nil && nonce > tx.Nonce() {
+				receipt = w.findReceipt(ctx, txs)
+				if receipt == nil {
+					return nil, fmt.Errorf("%w, nonce %d", ErrTxReplaced, tx.Nonce())
+				}
+				break
+			}
+			if w.known(ctx, txs) {
+				unknown = 0
+			} else if unknown++; unknown >= droppedTicks {
+				return nil, fmt.Errorf("%w, hash %s", ErrTxDropped, txs[len(txs)-1].Hash().String())
+			}
+			last := txs[len(txs)-1]
+			status := WaitStatus{Hash: last.Hash(), State: WaitPending, Want: want, Elapsed: time.Since(start)}
+			if bumping && time.Since(lastSent) >= w.BumpAfter && len(txs) <= w.MaxBumps {
+				status.State = WaitReplacing
+				w.progress(status)
+				bumped, err := w.Bump(ctx, last)
+				if err != nil {
+					// don't retry every poll, e.g. a watch-only account can never bump
+					bumping = false
+					continue
+				}
+				txs = append(txs, bumped)
+				watch(bumped)
+				lastSent = time.Now()
+				continue
+			}
+			w.progress(status)
+			continue
+		}
+
+		if receipt.Status == types.ReceiptStatusFailed {
+			w.progress(WaitStatus{Hash: receipt.TxHash, State: WaitMinedState, Confirmations: 1, Want: want, Elapsed: time.Since(start)})
+			return receipt, fmt.Errorf("%w, hash %s", ErrReverted, receipt.TxHash.String())
+		}
+		confirmed, err := w.confirmations(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		if confirmed == 0 {
+			// reorged out, back to pending
+			for _, t := range txs {
+				if t.Hash() == receipt.TxHash {
+					watch(t)
+				}
+			}
+			receipt = nil
+			continue
+		}
+		state := WaitMinedState
+		if confirmed >= want {
+			state = WaitConfirmed
+		}
+		w.progress(WaitStatus{Hash: receipt.TxHash, State: state, Confirmations: confirmed, Want: want, Elapsed: time.Since(start)})
+		if confirmed >= want {
+			return receipt, nil
+		}
+	}
+}
+
+// confirmations counts the blocks on top of the receipt, including its own.
+// It returns 0 if the receipt is no longer in the canonical chain.
+func (w *Waiter) confirmations(ctx context.Context, receipt *types.Receipt) (uint64, error) {
+	r, err := w.Backend.TransactionReceipt(ctx, receipt.TxHash)
+	if errors.Is(err, ethereum.NotFound) || (err == nil && r.BlockHash != receipt.BlockHash) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	head, err := w.Backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if head.Number.Cmp(r.BlockNumber) < 0 {
+		return 0, nil
+	}
+	return new(big.Int).Sub(head.Number, r.BlockNumber).Uint64() + 1, nil
+}
+
+func (w *Waiter) findReceipt(ctx context.Context, txs []*types.Transaction) *types.Receipt {
+	for _, t := range txs {
+		if r, err := w.Backend.TransactionReceipt(ctx, t.Hash()); err == nil && r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// known tells whether any of txs is still known to the node.
+func (w *Waiter) known(ctx context.Context, txs []*types.Transaction) bool {
+	for _, t := range txs {
+		_, _, err := w.Backend.TransactionByHash(ctx, t.Hash())
+		if err == nil || !errors.Is(err, ethereum.NotFound) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Waiter) progress(status WaitStatus) {
+	if w.Progress != nil {
+		w.Progress(status)
+	}
+}
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// waitChain is a simulated chain and the account 0 signing plain transfers
+// on it.
+type waitChain struct {
+	*backends.SimulatedBackend
+	signer Signer
+	t      *testing.T
+}
+
+func newWaitChain(t *testing.T) *waitChain {
+	t.Helper()
+	sim, cfg := newBare(t)
+	s, err := NewMnemonicSigner(cfg.Mnemonic, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &waitChain{SimulatedBackend: sim, signer: s, t: t}
+}
+
+// tx signs a transfer with nonce and gas price, without sending it.
+func (c *waitChain) tx(nonce uint64, gasPrice int64) *types.Transaction {
+	c.t.Helper()
+	to := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	tx, err := c.signer.SignTx(types.NewTransaction(nonce, to, big.NewInt(1), 21000, big.NewInt(gasPrice), nil), big.NewInt(testChainID))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return tx
+}
+
+func (c *waitChain) send(tx *types.Transaction) {
+	c.t.Helper()
+	if err := c.SendTransaction(context.Background(), tx); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *waitChain) waiter() *Waiter {
+	return &Waiter{Backend: c, Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+}
+
+func TestWaitConfirmed(t *testing.T) {
+	c := newWaitChain(t)
+	tx := c.tx(0, 1e9)
+	c.send(tx)
+	c.Commit()
+	c.Commit()
+	w := c.waiter()
+	w.Confirmations = 2
+	var states []string
+	w.Progress = func(s WaitStatus) { states = append(states, s.State) }
+	r, err := w.Wait(context.Background(), c.signer.Address(), tx)
+	if err != nil || r.TxHash != tx.Hash() {
+		t.Fatalf("Wait = %v, %v, want the receipt of %s", r, err, tx.Hash().Hex())
+	}
+	if last := states[len(states)-1]; last != WaitConfirmed {
+		t.Errorf("last state %s, want %s", last, WaitConfirmed)
+	}
+}
+
+func TestWaitDropped(t *testing.T) {
+	c := newWaitChain(t)
+	// signed but never sent: the node doesn't know it
+	tx := c.tx(0, 1e9)
+	w := c.waiter()
+	polls := 0
+	w.Progress = func(WaitStatus) { polls++ }
+	_, err := w.Wait(context.Background(), c.signer.Address(), tx)
+	if !errors.Is(err, ErrTxDropped) {
+		t.Fatalf("err = %v, want ErrTxDropped", err)
+	}
+	// the first ticks are given the benefit of the doubt
+	if polls != droppedTicks-1 {
+		t.Errorf("dropped after %d pending polls, want %d", polls, droppedTicks-1)
+	}
+
+	// known again before the third tick: still pending
+	c = newWaitChain(t)
+	tx = c.tx(0, 1e9)
+	w = c.waiter()
+	polls = 0
+	w.Progress = func(WaitStatus) {
+		if polls++; polls == droppedTicks-1 {
+			c.send(tx)
+		}
+		if polls == droppedTicks+1 {
+			c.Commit()
+		}
+	}
+	if r, err := w.Wait(context.Background(), c.signer.Address(), tx); err != nil || r.TxHash != tx.Hash() {
+		t.Fatalf("late propagation: Wait = %v, %v, want the receipt", r, err)
+	}
+}
+
+func TestWaitReplaced(t *testing.T) {
+	c := newWaitChain(t)
+	tx := c.tx(0, 1e9)
+	// another transaction of the account takes the nonce
+	c.send(c.tx(0, 2e9))
+	c.Commit()
+	_, err := c.waiter().Wait(context.Background(), c.signer.Address(), tx)
+	if !errors.Is(err, ErrTxReplaced) {
+		t.Fatalf("err = %v, want ErrTxReplaced", err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	c := newWaitChain(t)
+	// pending and never mined
+	tx := c.tx(0, 1e9)
+	c.send(tx)
+	w := c.waiter()
+	w.Timeout = 100 * time.Millisecond
+	_, err := w.Wait(context.Background(), c.signer.Address(), tx)
+	var timeout *WaitTimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("err = %v, want a WaitTimeoutError", err)
+	}
+	if timeout.Tx.Hash() != tx.Hash() || timeout.Timeout != w.Timeout {
+		t.Errorf("timeout of %s after %s, want %s after %s", timeout.Tx.Hash().Hex(), timeout.Timeout, tx.Hash().Hex(), w.Timeout)
+	}
+
+	// the caller cancelling is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = w.Wait(ctx, c.signer.Address(), tx); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestWaitBump(t *testing.T) {
+	c := newWaitChain(t)
+	// stuck in another node's pool, at a price too low to be mined
+	tx := c.tx(0, 1e9)
+	var bumped []*types.Transaction
+	w := c.waiter()
+	w.BumpAfter = w.Interval
+	w.MaxBumps = 2
+	w.Bump = func(ctx context.Context, last *types.Transaction) (*types.Transaction, error) {
+		replacement := c.tx(last.Nonce(), 2*last.GasPrice().Int64())
+		bumped = append(bumped, replacement)
+		c.send(replacement)
+		c.Commit()
+		return replacement, nil
+	}
+	r, err := w.Wait(context.Background(), c.signer.Address(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bumped) != 1 || r.TxHash != bumped[0].Hash() {
+		t.Fatalf("receipt of %s after %d bumps, want the one replacement", r.TxHash.Hex(), len(bumped))
+	}
+
+	// a failing bump is not retried, the original is still waited for
+	c = newWaitChain(t)
+	tx = c.tx(0, 1e9)
+	c.send(tx)
+	w = c.waiter()
+	w.BumpAfter = w.Interval
+	w.MaxBumps = 2
+	calls := 0
+	w.Bump = func(ctx context.Context, last *types.Transaction) (*types.Transaction, error) {
+		calls++
+		c.Commit()
+		return nil, errors.New("watch-only account")
+	}
+	if r, err = w.Wait(context.Background(), c.signer.Address(), tx); err != nil || r.TxHash != tx.Hash() {
+		t.Fatalf("failed bump: Wait = %v, %v, want the receipt of the original", r, err)
+	}
+	if calls != 1 {
+		t.Errorf("Bump called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	hdwallet "github.com/miguelmota/go-ethereum-hdwallet"
+	"strings"
+)
+
+// DefaultDerivationPath is the BIP44 path used by MetaMask, Trezor and most wallets.
+const DefaultDerivationPath = "m/44'/60'/0'/0/%d"
+
+// DerivationPaths are the well known path templates, %d is replaced by the account index.
+var DerivationPaths = map[string]string{
+	"default":     DefaultDerivationPath,
+	"ledger-live": "m/44'/60'/%d'/0/0",
+	"legacy":      "m/44'/60'/0'/%d", // legacy MEW and Ledger
+}
+
+// DerivationPath resolves template, a name in DerivationPaths or a custom path
+// containing one %d, for account. An empty template means the default path.
+func DerivationPath(template string, account int) (accounts.DerivationPath, error) {
+	if template == "" {
+		template = DefaultDerivationPath
+	}
+	if t, ok := DerivationPaths[template]; ok {
+		template = t
+	}
+	if strings.Count(template, "%d") != 1 {
+		return nil, fmt.Errorf("derivation path %s should contain one %%d for the account", template)
+	}
+	return hdwallet.ParseDerivationPath(fmt.Sprintf(template, account))
+}
+
+// DerivedAccount is an account derived from the mnemonic.
+type DerivedAccount struct {
+	Index   int
+	Path    string
+	Address common.Address
+}
+
+// DeriveAccounts derives the first n accounts from the mnemonic stored in
+// filename, which must not be readable by others, as for the node's signer.
+func DeriveAccounts(filename, template string, n int) ([]DerivedAccount, error) {
+	wallet, err := loadWallet(filename)
+	if err != nil {
+		return nil, err
+	}
+	var list []DerivedAccount
+	for i := 0; i < n; i++ {
+		path, err := DerivationPath(template, i)
+		if err != nil {
+			return nil, err
+		}
+		a, err := wallet.Derive(path, false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, DerivedAccount{Index: i, Path: path.String(), Address: a.Address})
+	}
+	return list, nil
+}
+
+// loadWallet reads the mnemonic, refusing a file readable by others.
+func loadWallet(filename string) (*hdwallet.Wallet, error) {
+	if err := checkSecretFile("mnemonic", filename); err != nil {
+		return nil, err
+	}
+	mnemonic, err := loadMnemonic(filename)
+	if err != nil {
+		return nil, err
+	}
+	return hdwallet.NewFromMnemonic(mnemonic)
+}
@@ -0,0 +1,33 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDeriveAccounts(t *testing.T) {
+	mnemonic := filepath.Join(t.TempDir(), "mnemonic")
+	if err := os.WriteFile(mnemonic, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := DeriveAccounts(mnemonic, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"); len(list) != 2 || list[0].Address != want {
+		t.Fatalf("DeriveAccounts = %v, want account 0 %s", list, want.Hex())
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err = os.Chmod(mnemonic, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DeriveAccounts(mnemonic, "", 2); err == nil {
+		t.Error("mnemonic readable by others: want an error")
+	}
+}
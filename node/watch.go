@@ -0,0 +1,209 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"time"
+)
+
+// ContractEvent is a decoded log of the contract.
+type ContractEvent struct {
+	// Name is the event, e.g. Transfer or Paused.
+	Name  string
+	Block uint64
+	Tx    common.Hash
+	Index uint
+	// Account of Paused and Unpaused.
+	Account common.Address
+	// From and To of Transfer, the previous and new owner of OwnershipTransferred.
+	From common.Address
+	To   common.Address
+	// TokenId of Transfer.
+	TokenId *big.Int
+}
+
+func (e ContractEvent) String() string {
+	switch e.Name {
+	case "Transfer":
+		return fmt.Sprintf("Transfer of token %s from %s to %s", e.TokenId, e.From.Hex(), e.To.Hex())
+	case "Paused", "Unpaused":
+		return fmt.Sprintf("%s by %s", e.Name, e.Account.Hex())
+	case "OwnershipTransferred":
+		return fmt.Sprintf("OwnershipTransferred from %s to %s", e.From.Hex(), e.To.Hex())
+	}
+	return e.Name
+}
+
+// decodeEvent decodes the log of the events watched; ok is false for other
+// logs, e.g. Approval.
+func (n *Node) decodeEvent(l types.Log) (ContractEvent, bool, error) {
+	e := ContractEvent{Block: l.BlockNumber, Tx: l.TxHash, Index: l.Index}
+	if len(l.Topics) == 0 {
+		return e, false, nil
+	}
+	event, err := carABI.EventByID(l.Topics[0])
+	if err != nil {
+		return e, false, nil
+	}
+	e.Name = event.Name
+	switch e.Name {
+	case "Transfer":
+		t, err := n.nft.ParseTransfer(l)
+		if err != nil {
+			return e, false, err
+		}
+		e.From, e.To, e.TokenId = t.From, t.To, t.TokenId
+	case "Paused":
+		p, err := n.nft.ParsePaused(l)
+		if err != nil {
+			return e, false, err
+		}
+		e.Account = p.Account
+	case "Unpaused":
+		u, err := n.nft.ParseUnpaused(l)
+		if err != nil {
+			return e, false, err
+		}
+		e.Account = u.Account
+	case "OwnershipTransferred":
+		o, err := n.nft.ParseOwnershipTransferred(l)
+		if err != nil {
+			return e, false, err
+		}
+		e.From, e.To = o.PreviousOwner, o.NewOwner
+	default:
+		return e, false, nil
+	}
+	return e, true, nil
+}
+
+// defaultMaxRange is the blocks of one log request.
+const defaultMaxRange = 2000
+
+// WatchOptions controls Watch.
+type WatchOptions struct {
+	// From is the first block to read, the latest block if 0.
+	From uint64
+	// Confirmations is how far behind the latest block the logs are read, so
+	// that reorgs rarely replay them.
+	Confirmations uint64
+	// Interval between polls of the latest block, default 15 seconds. It is
+	// also the wait before retrying a failed request.
+	Interval time.Duration
+	// MaxRange limits the blocks of one log request, default 2000.
+	MaxRange uint64
+	// Handle is given the events of every block range read, in order, also
+	// when there are none.
+	Handle func(from, to uint64, events []ContractEvent)
+}
+
+// Watch reads the Transfer, Paused, Unpaused and OwnershipTransferred events
+// of the contract as blocks come, until ctx is done. Failed requests are
+// logged and retried, so Watch only returns the error of ctx.
+func (n *Node) Watch(ctx context.Context, opts WatchOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	maxRange := opts.MaxRange
+	if maxRange == 0 {
+		maxRange = defaultMaxRange
+	}
+	next := opts.From
+	for {
+		behind, err := n.watchRange(ctx, &next, opts.Confirmations, maxRange, opts.Handle)
+		if err != nil && ctx.Err() == nil {
+			n.Sugar.Warnf("watch logs error, retry in %s: %s", interval, err)
+		}
+		if behind {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// watchRange reads the next range of blocks up to the confirmed head and
+// advances next past it, behind tells there are more blocks to read already.
+func (n *Node) watchRange(ctx context.Context, next *uint64, confirmations, maxRange uint64, handle func(uint64, uint64, []ContractEvent)) (behind bool, err error) {
+	head, err := n.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	latest := head.Number.Uint64()
+	if latest < confirmations {
+		return false, nil
+	}
+	latest -= confirmations
+	if *next == 0 {
+		*next = latest
+	}
+	if *next > latest {
+		return false, nil
+	}
+	from, to := *next, latest
+	if to-from+1 > maxRange {
+		to = from + maxRange - 1
+	}
+	logs, err := n.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{n.contract},
+	})
+	if err != nil {
+		return false, err
+	}
+	var events []ContractEvent
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		e, ok, err := n.decodeEvent(l)
+		if err != nil {
+			n.Sugar.Warnf("decode log %d of tx %s error: %s", l.Index, l.TxHash.String(), err)
+			continue
+		}
+		if ok {
+			events = append(events, e)
+		}
+	}
+	if handle != nil {
+		handle(from, to, events)
+	}
+	*next = to + 1
+	return to < latest, nil
+}
+
+// WatchNotify watches the contract and notifies Paused, Unpaused,
+// OwnershipTransferred and bursts of transfers, see Config.Notify.
+// opts.Handle, if set, is given the events too.
+func (n *Node) WatchNotify(ctx context.Context, opts WatchOptions) error {
+	if n.notifier == nil {
+		n.Sugar.Warn("no notify channel configured, events are only logged")
+	}
+	handle := opts.Handle
+	opts.Handle = func(from, to uint64, events []ContractEvent) {
+		n.Sugar.Debugf("blocks %d-%d: %d events", from, to, len(events))
+		if n.notifier != nil {
+			for _, e := range n.notifier.ContractEvents(events) {
+				n.Sugar.Infof("%s in block %d", e.Kind, e.Block)
+				n.notify(ctx, e)
+			}
+		} else {
+			for _, e := range events {
+				n.Sugar.Infof("%s in block %d, tx %s", e, e.Block, e.Tx.Hex())
+			}
+		}
+		if handle != nil {
+			handle(from, to, events)
+		}
+	}
+	return n.Watch(ctx, opts)
+}